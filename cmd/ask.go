@@ -10,7 +10,11 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var fileContext string
+var (
+	fileContext string
+	askImages   []string
+	askRepo     bool
+)
 
 var askCmd = &cobra.Command{
 	Use:   "ask [prompt]",
@@ -19,7 +23,17 @@ var askCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		prompt := strings.Join(args, " ")
 
-		provider, err := ai.NewProvider()
+		var images []ai.Image
+		for _, path := range askImages {
+			img, err := ai.LoadImage(path)
+			if err != nil {
+				color.Red("Error: %v", err)
+				return
+			}
+			images = append(images, img)
+		}
+
+		provider, err := newProvider()
 		if err != nil {
 			color.Red("Error: %v", err)
 			return
@@ -27,33 +41,81 @@ var askCmd = &cobra.Command{
 
 		finalPrompt := prompt
 		if fileContext != "" {
-			content, _ := os.ReadFile(fileContext)
+			finalPrompt, err = withFileContext(fileContext, prompt)
+			if err != nil {
+				color.Red("Error: %v", err)
+				return
+			}
+			fmt.Printf("Using context from: %s\n", fileContext)
+		}
 
-			sb := strings.Builder{}
-			sb.WriteString("Context file (")
-			sb.WriteString(fileContext)
-			sb.WriteString("):\n\n")
-			sb.WriteString(string(content))
-			sb.WriteString("\n\nQuestion: ")
-			sb.WriteString(prompt)
+		if askRepo {
+			repo, err := repoContext(prompt)
+			if err != nil {
+				color.Red("Error: %v", err)
+				printGuidance(err)
+				return
+			}
+			if fileContext == "" {
+				finalPrompt = "Question: " + finalPrompt
+			}
+			finalPrompt = repo + "\n\n" + finalPrompt
+			fmt.Println("Using context from the repository index")
+		}
 
-			finalPrompt = sb.String()
+		if len(images) > 0 {
+			fmt.Printf("Attaching %d image(s)\n", len(images))
+			provider.Attach(images...)
+		}
 
-			fmt.Printf("Using context from: %s\n", fileContext)
+		if err := checkPromptFits(os.Stdout, provider, finalPrompt, len(images)); err != nil {
+			color.Red("Error: %v", err)
+			printGuidance(err)
+			return
 		}
 
+		provider.SetOptions(generationOptions("ask"))
 		fmt.Printf("Asking %s...\n", provider.Name())
-		response, err := provider.Send(finalPrompt)
+		ctx, stop := commandContext("ask")
+		defer stop()
+
+		_, err = streamReply(ctx, provider, finalPrompt, "Thinking", func() {
+			fmt.Println()
+		})
+		if isCancelled(err) {
+			color.Yellow("\nRequest cancelled")
+			return
+		}
 		if err != nil {
 			color.Red("Failed: %v", err)
+			printGuidance(err)
 			return
 		}
-
-		color.Cyan("\n%s\n", response)
+		fmt.Println()
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(askCmd)
 	askCmd.Flags().StringVarP(&fileContext, "file", "f", "", "Attach file context")
+	askCmd.Flags().StringArrayVar(&askImages, "image", nil, "Attach an image (PNG, JPEG, GIF, WebP); repeatable")
+	askCmd.Flags().BoolVar(&askRepo, "repo", false, "Answer from the most relevant code in the index built by 'forge index'")
+}
+
+// withFileContext puts the contents of path ahead of question, the way ask
+// and compare send --file.
+func withFileContext(path, question string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString("Context file (")
+	sb.WriteString(path)
+	sb.WriteString("):\n\n")
+	sb.WriteString(string(content))
+	sb.WriteString("\n\nQuestion: ")
+	sb.WriteString(question)
+	return sb.String(), nil
 }
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/broman0x/forgeai-cli/internal/ai"
+	"github.com/broman0x/forgeai-cli/internal/ui"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	compareModels string
+	compareFile   string
+)
+
+var compareCmd = &cobra.Command{
+	Use:   "compare --models provider:model,... [prompt]",
+	Short: "Send one prompt to several models and compare the answers",
+	Example: `  forge compare --models gemini:gemini-2.5-flash,ollama:llama3 "Explain Go channels"
+  forge compare --models openai:gpt-4o-mini,claude --file main.go "Find bugs"`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		prompt := strings.Join(args, " ")
+
+		specs, err := parseModelSpecs(compareModels)
+		if err != nil {
+			color.Red("  Error: %v", err)
+			return
+		}
+
+		if compareFile != "" {
+			prompt, err = withFileContext(compareFile, prompt)
+			if err != nil {
+				color.Red("  Error: %v", err)
+				return
+			}
+		}
+
+		var provs []ai.Provider
+		for _, s := range specs {
+			prov, err := ai.CreateProvider(s.provider, s.model)
+			if err != nil {
+				color.Yellow("  ⚠ Skipping %s: %v", s, err)
+				continue
+			}
+			provs = append(provs, prov)
+		}
+		if len(provs) == 0 {
+			color.Red("  Error: none of the models could be set up")
+			return
+		}
+
+		runCompare(os.Stdout, provs, prompt)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(compareCmd)
+	compareCmd.Flags().StringVarP(&compareModels, "models", "m", "", "Comma-separated provider:model pairs; the model is optional")
+	compareCmd.Flags().StringVarP(&compareFile, "file", "f", "", "Attach file context")
+	compareCmd.MarkFlagRequired("models")
+}
+
+type modelSpec struct {
+	provider string
+	model    string
+}
+
+func (s modelSpec) String() string {
+	if s.model == "" {
+		return s.provider
+	}
+	return s.provider + ":" + s.model
+}
+
+// parseModelSpecs reads "gemini:gemini-2.5-flash,ollama:llama3:8b". Only the
+// first colon separates the provider, since Ollama tags contain one too.
+func parseModelSpecs(value string) ([]modelSpec, error) {
+	var specs []modelSpec
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		provider, model, _ := strings.Cut(part, ":")
+		specs = append(specs, modelSpec{provider: strings.TrimSpace(provider), model: strings.TrimSpace(model)})
+	}
+	if len(specs) < 2 {
+		return nil, fmt.Errorf("compare needs at least two models, e.g. --models gemini:gemini-2.5-flash,ollama:llama3")
+	}
+	return specs, nil
+}
+
+type compareResult struct {
+	prov    ai.Provider
+	answer  string
+	err     error
+	latency time.Duration
+	usage   ai.Usage
+}
+
+// runCompare sends prompt to every provider at once and prints the answers
+// in the order given, followed by a latency and token table. A model the
+// prompt does not fit is reported like a failed request.
+func runCompare(out io.Writer, provs []ai.Provider, prompt string) {
+	results := make([]compareResult, len(provs))
+	asked := 0
+	for i, prov := range provs {
+		results[i].prov = prov
+		if results[i].err = checkPromptFits(out, prov, prompt, 0); results[i].err == nil {
+			prov.SetOptions(generationOptions("compare"))
+			asked++
+		}
+	}
+
+	ctx, stop := commandContext("compare")
+	defer stop()
+
+	spinner := ui.NewSpinner(fmt.Sprintf("Asking %d models", asked))
+	spinner.Start()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	pending := asked
+	for i, prov := range provs {
+		if results[i].err != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(r *compareResult, prov ai.Provider) {
+			defer wg.Done()
+
+			callCtx := ai.WithUsageObserver(ctx, func(u ai.Usage) {
+				r.usage.InputTokens += u.InputTokens
+				r.usage.OutputTokens += u.OutputTokens
+			})
+			start := time.Now()
+			r.answer, r.err = prov.Send(callCtx, prompt)
+			r.latency = time.Since(start)
+
+			mu.Lock()
+			pending--
+			spinner.SetMessage(fmt.Sprintf("Waiting for %d of %d models", pending, asked))
+			mu.Unlock()
+		}(&results[i], prov)
+	}
+	wg.Wait()
+	spinner.Stop()
+
+	if isCancelled(ctx.Err()) {
+		fmt.Fprintln(out, color.YellowString("  Comparison cancelled"))
+		return
+	}
+
+	cTitle := color.New(color.FgHiCyan, color.Bold).SprintFunc()
+	cSubtle := color.New(color.FgHiBlack).SprintFunc()
+	md := ui.NewMarkdownRenderer()
+
+	for _, r := range results {
+		fmt.Fprintln(out)
+		fmt.Fprintf(out, "  %s\n", cTitle(r.prov.Name()))
+		fmt.Fprintln(out, cSubtle("  ───────────────────────────────────────────"))
+		if r.err != nil {
+			fmt.Fprintln(out, color.RedString("  Error: %v", r.err))
+			if hint := errorGuidance(r.err); hint != "" {
+				fmt.Fprintln(out, color.YellowString("  %s", hint))
+			}
+			continue
+		}
+		fmt.Fprintln(out, md.Render(r.answer))
+	}
+
+	fmt.Fprintln(out)
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  MODEL\tLATENCY\tINPUT\tOUTPUT\tCOST")
+	for _, r := range results {
+		if r.err != nil {
+			fmt.Fprintf(w, "  %s\tfailed\t-\t-\t-\n", r.prov.Name())
+			continue
+		}
+		in, outTokens, cost := "-", "-", "-"
+		if r.usage.InputTokens > 0 || r.usage.OutputTokens > 0 {
+			in, outTokens = fmt.Sprint(r.usage.InputTokens), fmt.Sprint(r.usage.OutputTokens)
+			cost = formatCost(r.usage.Cost(r.prov.ModelName()))
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", r.prov.Name(), r.latency.Round(10*time.Millisecond), in, outTokens, cost)
+	}
+	w.Flush()
+	fmt.Fprintln(out)
+}
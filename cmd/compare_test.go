@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/broman0x/forgeai-cli/internal/ai"
+	"github.com/broman0x/forgeai-cli/internal/ai/aitest"
+)
+
+func TestParseModelSpecs(t *testing.T) {
+	specs, err := parseModelSpecs("gemini:gemini-2.5-flash, ollama:llama3:8b,claude")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []modelSpec{{"gemini", "gemini-2.5-flash"}, {"ollama", "llama3:8b"}, {"claude", ""}}
+	if len(specs) != len(want) {
+		t.Fatalf("got %+v", specs)
+	}
+	for i := range want {
+		if specs[i] != want[i] {
+			t.Fatalf("spec %d: got %+v, want %+v", i, specs[i], want[i])
+		}
+	}
+
+	if _, err := parseModelSpecs("gemini"); err == nil {
+		t.Fatal("expected an error for a single model")
+	}
+}
+
+func TestCompareShowsEveryAnswerInOrder(t *testing.T) {
+	setupTest(t)
+	fast := aitest.NewFakeProvider("Channels pass values between goroutines.")
+	fast.Model = "fast"
+	broken := &aitest.FakeProvider{Model: "broken", Replies: []aitest.Reply{{Err: &ai.AuthError{APIError: &ai.APIError{Provider: "openai", StatusCode: 401, Message: "bad key"}}}}}
+	slow := aitest.NewFakeProvider("A channel is a typed pipe.")
+	slow.Model = "slow"
+
+	var out bytes.Buffer
+	captureOutput(t, func() {
+		runCompare(&out, []ai.Provider{fast, broken, slow}, "Explain Go channels")
+	})
+
+	for _, fake := range []*aitest.FakeProvider{fast, broken, slow} {
+		if calls := fake.Calls(); len(calls) != 1 || calls[0].Prompt != "Explain Go channels" {
+			t.Fatalf("%s got calls %+v", fake.Name(), calls)
+		}
+	}
+
+	text := out.String()
+	first := strings.Index(text, "Channels pass values")
+	second := strings.Index(text, "bad key")
+	third := strings.Index(text, "A channel is a typed pipe.")
+	if first < 0 || second < first || third < second {
+		t.Fatalf("answers missing or out of order:\n%s", text)
+	}
+	if !strings.Contains(text, "LATENCY") || !strings.Contains(text, "Fake (broken)  failed") {
+		t.Fatalf("summary table missing:\n%s", text)
+	}
+}
+
+func TestCompareSkipsOnlyTheModelThePromptDoesNotFit(t *testing.T) {
+	setupTest(t)
+	small := aitest.NewFakeProvider("ok")
+	small.Model = "phi3"
+	big := aitest.NewFakeProvider("Plenty of room.")
+	big.Model = "gpt-4o"
+
+	var out bytes.Buffer
+	captureOutput(t, func() {
+		runCompare(&out, []ai.Provider{small, big}, strings.Repeat("x", 4*10000))
+	})
+
+	if len(small.Calls()) != 0 || len(big.Calls()) != 1 {
+		t.Fatalf("phi3 got %d calls, gpt-4o %d; want 0 and 1", len(small.Calls()), len(big.Calls()))
+	}
+	text := out.String()
+	if !strings.Contains(text, "Plenty of room.") || !strings.Contains(text, "phi3 takes at most") {
+		t.Fatalf("expected phi3 reported as failed next to the gpt-4o answer:\n%s", text)
+	}
+}
@@ -22,7 +22,11 @@ var editCmd = &cobra.Command{
 			cmd.Help()
 			return
 		}
-		prov, _ := ai.NewProvider()
+		prov, err := newProvider()
+		if err != nil {
+			color.Red("Error: %v", err)
+			return
+		}
 		runEditLogic(prov, args[0], args[1], nil)
 	},
 }
@@ -31,6 +35,66 @@ func init() {
 	rootCmd.AddCommand(editCmd)
 }
 
+const editSystemPrompt = `You are a world-class senior software engineer with deep expertise in software architecture and every mainstream language.
+
+You receive an instruction, the target file and its current code (if any).
+
+IMPORTANT - UNDERSTAND THE INTENT:
+- If instruction says "buatkan/create/add/implement NEW feature" → CREATE completely new code/content
+- If instruction says "modify/change/fix/update EXISTING" → MODIFY the existing code
+- If file is empty or minimal → User wants you to CREATE from scratch
+- If instruction is about design/UI → Create visually stunning, modern, professional design
+
+When CREATING NEW (e.g., landing pages, components, features):
+1. START FROM SCRATCH - Don't just modify what's there
+2. IMPLEMENT COMPLETE SOLUTION with all requested features
+3. USE MODERN DESIGN:
+   - Beautiful color schemes (gradients, modern palettes)
+   - Responsive layouts (mobile-first)
+   - Smooth animations and transitions
+   - Premium aesthetics (glassmorphism, shadows, etc.)
+   - Professional typography
+4. INCLUDE ALL NECESSARY CODE (HTML + CSS + JS if needed)
+5. Make it PRODUCTION-READY and VISUALLY IMPRESSIVE
+
+When MODIFYING EXISTING:
+1. PRESERVE original structure and intent
+2. APPLY requested changes cleanly
+3. IMPROVE code quality and best practices
+4. FIX bugs and add error handling
+
+CODE QUALITY STANDARDS:
+1. BEST PRACTICES: Industry standards & design patterns
+2. CLEAN CODE: Readable, maintainable, DRY principles
+3. OPTIMIZATION: Performance-first approach
+4. SECURITY: Input validation, prevent vulnerabilities
+5. ERROR HANDLING: Proper error handling & edge cases
+6. MODERN SYNTAX: Use latest language features
+7. COMMENTS: Only for complex business logic
+
+ARCHITECTURE PRINCIPLES:
+- Single Responsibility Principle
+- DRY (Don't Repeat Yourself)
+- SOLID principles when applicable
+- Clean separation of concerns
+- Modular and reusable code
+
+FOR WEB DEVELOPMENT:
+- Semantic HTML5 elements
+- Modern CSS (Flexbox, Grid, CSS Variables, animations)
+- Responsive design (mobile-first)
+- Accessibility (ARIA labels, semantic markup)
+- Performance optimization (lazy loading, efficient selectors)
+- Beautiful, modern UI/UX design
+- Professional color schemes and typography
+
+OUTPUT REQUIREMENTS:
+- Return ONLY the complete code
+- NO explanations, NO markdown blocks, NO comments about changes
+- Code must be immediately usable
+- If creating new: Make it COMPLETE and IMPRESSIVE
+- If modifying: Preserve working parts, improve requested areas`
+
 func StartEditModeInteractive(scanner *bufio.Scanner, prov ai.Provider) {
 	cTitle := color.New(color.FgHiCyan, color.Bold).SprintFunc()
 	cPrompt := color.New(color.FgWhite).SprintFunc()
@@ -110,6 +174,10 @@ func StartEditModeInteractive(scanner *bufio.Scanner, prov ai.Provider) {
 }
 
 func runEditLogic(prov ai.Provider, filePath, instruction string, scanner *bufio.Scanner) {
+	prov = withResponseCache(prov)
+	prov.SetSystemPrompt(editSystemPrompt)
+	prov.SetOptions(generationOptions("edit"))
+
 	isDir := false
 	info, err := os.Stat(filePath)
 	if err == nil && info.IsDir() {
@@ -194,81 +262,37 @@ func runEditLogic(prov ai.Provider, filePath, instruction string, scanner *bufio
 	ext := filepath.Ext(filePath)
 	lang := detectLanguage(ext)
 
-	spinner := ui.NewSpinner("Processing request")
-	spinner.Start()
-
-	prompt := fmt.Sprintf(`You are a world-class senior software engineer with deep expertise in %s and software architecture.
-
-TASK: Execute this instruction: "%s"
-
-IMPORTANT - UNDERSTAND THE INTENT:
-- If instruction says "buatkan/create/add/implement NEW feature" → CREATE completely new code/content
-- If instruction says "modify/change/fix/update EXISTING" → MODIFY the existing code
-- If file is empty or minimal → User wants you to CREATE from scratch
-- If instruction is about design/UI → Create visually stunning, modern, professional design
-
-When CREATING NEW (e.g., landing pages, components, features):
-1. START FROM SCRATCH - Don't just modify what's there
-2. IMPLEMENT COMPLETE SOLUTION with all requested features
-3. USE MODERN DESIGN:
-   - Beautiful color schemes (gradients, modern palettes)
-   - Responsive layouts (mobile-first)
-   - Smooth animations and transitions
-   - Premium aesthetics (glassmorphism, shadows, etc.)
-   - Professional typography
-4. INCLUDE ALL NECESSARY CODE (HTML + CSS + JS if needed)
-5. Make it PRODUCTION-READY and VISUALLY IMPRESSIVE
-
-When MODIFYING EXISTING:
-1. PRESERVE original structure and intent
-2. APPLY requested changes cleanly
-3. IMPROVE code quality and best practices
-4. FIX bugs and add error handling
-
-CODE QUALITY STANDARDS:
-1. BEST PRACTICES: Industry standards & design patterns
-2. CLEAN CODE: Readable, maintainable, DRY principles
-3. OPTIMIZATION: Performance-first approach
-4. SECURITY: Input validation, prevent vulnerabilities
-5. ERROR HANDLING: Proper error handling & edge cases
-6. MODERN SYNTAX: Use latest language features
-7. COMMENTS: Only for complex business logic
-
-ARCHITECTURE PRINCIPLES:
-- Single Responsibility Principle
-- DRY (Don't Repeat Yourself)
-- SOLID principles when applicable
-- Clean separation of concerns
-- Modular and reusable code
-
-FOR WEB DEVELOPMENT (%s):
-- Semantic HTML5 elements
-- Modern CSS (Flexbox, Grid, CSS Variables, animations)
-- Responsive design (mobile-first)
-- Accessibility (ARIA labels, semantic markup)
-- Performance optimization (lazy loading, efficient selectors)
-- Beautiful, modern UI/UX design
-- Professional color schemes and typography
-
-OUTPUT REQUIREMENTS:
-- Return ONLY the complete code
-- NO explanations, NO markdown blocks, NO comments about changes
-- Code must be immediately usable
-- If creating new: Make it COMPLETE and IMPRESSIVE
-- If modifying: Preserve working parts, improve requested areas
+	prompt := fmt.Sprintf(`TASK: Execute this instruction: "%s"
 
+Language: %s
 Current File: %s
 
 Current Code (if any):
 %s
 
-EXECUTE THE INSTRUCTION ABOVE. If user wants something NEW, create it from scratch. If they want to modify, improve the existing code.`, lang, instruction, lang, filePath, string(content))
+EXECUTE THE INSTRUCTION ABOVE. If user wants something NEW, create it from scratch. If they want to modify, improve the existing code.`, instruction, lang, filePath, string(content))
 
-	newCode, err := prov.Send(prompt)
+	if err := checkPromptFits(os.Stdout, prov, prompt, 0); err != nil {
+		color.Red("  Error: %v", err)
+		printGuidance(err)
+		return
+	}
+
+	spinner := ui.NewSpinner("Processing request")
+	spinner.Start()
+
+	ctx, stop := commandContext("edit")
+	newCode, err := prov.Send(showRateLimitWait(ctx, spinner, "Processing request"), prompt)
+	stop()
 	spinner.Stop()
 
+	if isCancelled(err) {
+		color.Yellow("  Request cancelled")
+		return
+	}
 	if err != nil {
 		color.Red("  Error: %v", err)
+		printGuidance(err)
 		return
 	}
 
@@ -575,11 +599,18 @@ DO NOT START WITH "Here is the code".
 OUTPUT (ONLY CODE):`, filename, instruction, lang, fileInstruction)
 		}
 
-		code, err := prov.Send(prompt)
+		ctx, stop := commandContext("edit")
+		code, err := prov.Send(showRateLimitWait(ctx, spinner, "Generating code"), prompt)
+		stop()
 		spinner.Stop()
 
+		if isCancelled(err) {
+			color.Yellow("  Cancelled. Remaining files were not created.")
+			return
+		}
 		if err != nil {
 			color.Red("  ! Error generating %s: %v", filename, err)
+			printGuidance(err)
 			continue
 		}
 
@@ -626,6 +657,26 @@ func isGeneralInstruction(instruction string) bool {
 	return false
 }
 
+type agentResult struct {
+	Changed bool   `json:"changed"`
+	Summary string `json:"summary"`
+	Content string `json:"content"`
+}
+
+var agentSchema = ai.Schema{
+	Name:        "file_update",
+	Description: "The agent's decision for one file.",
+	Schema: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"changed": map[string]interface{}{"type": "boolean", "description": "Whether the file needs to change."},
+			"summary": map[string]interface{}{"type": "string", "description": "One sentence on what changed or why nothing did."},
+			"content": map[string]interface{}{"type": "string", "description": "The complete updated file, or an empty string when unchanged."},
+		},
+		"required": []string{"changed", "summary", "content"},
+	},
+}
+
 func handleProjectAgentMode(prov ai.Provider, dirPath, instruction string, scanner *bufio.Scanner) {
 	cTitle := color.New(color.FgHiMagenta, color.Bold).SprintFunc()
 	cSubtle := color.New(color.FgHiBlack).SprintFunc()
@@ -680,9 +731,6 @@ func handleProjectAgentMode(prov ai.Provider, dirPath, instruction string, scann
 			continue
 		}
 
-		spinner := ui.NewSpinner("  Agent is thinking")
-		spinner.Start()
-
 		ext := filepath.Ext(filename)
 		lang := detectLanguage(ext)
 
@@ -696,28 +744,54 @@ LANGUAGE: %s
 CRITICAL RULES:
 1. FOCUS ONLY ON THIS FILE (%s). Do NOT generate code for other files.
 2. Analyze the code based on the user instruction.
-3. If the instruction applies to this file, MODIFY the code.
-4. If the instruction does NOT apply (e.g. instruction is 'fix css' but this is 'script.js'), return the code EXACTLY AS IS.
-5. DO NOT START WITH "Here is the code" or "I have fixed it".
-6. RETURN ONLY THE CODE. NO MARKDOWN BLOCK START/END.
+3. If the instruction applies to this file, MODIFY the code and set "changed" to true.
+4. If the instruction does NOT apply (e.g. instruction is 'fix css' but this is 'script.js'), set "changed" to false.
+5. "content" is the COMPLETE new file when changed, without markdown fences.
+6. "summary" is one short sentence describing what you did or why nothing changed.
 
 CODE CONTENT:
-%s
+%s`, instruction, filename, ext, lang, filename, string(content))
+
+		if err := checkPromptFits(os.Stdout, prov, prompt, 0); err != nil {
+			color.Red("  Agent error: %v", err)
+			printGuidance(err)
+			continue
+		}
 
-OUTPUT (ONLY CODE):`, instruction, filename, ext, lang, filename, string(content))
+		spinner := ui.NewSpinner("  Agent is thinking")
+		spinner.Start()
 
-		newCode, err := prov.Send(prompt)
+		var result agentResult
+		ctx, stop := commandContext("agent")
+		err = ai.SendJSON(showRateLimitWait(ctx, spinner, "  Agent is thinking"), prov, prompt, agentSchema, &result)
+		stop()
 		spinner.Stop()
 
+		if isCancelled(err) {
+			color.Yellow("  Agent cancelled.")
+			break
+		}
 		if err != nil {
 			color.Red("  Agent error: %v", err)
+			printGuidance(err)
 			continue
 		}
 
-		newCode = cleanMarkdown(newCode)
+		// Models often fence the file even inside the JSON string. The schema
+		// can't require content only when changed; a blank file here is a
+		// broken reply, not an edit.
+		result.Content = cleanMarkdown(result.Content)
+		if result.Changed && strings.TrimSpace(result.Content) == "" {
+			color.Red("  Agent error: %s was marked changed but came back empty; left as is.", filename)
+			continue
+		}
 
-		if strings.Contains(newCode, "/* style.css */") || strings.Contains(newCode, "<!-- index.html -->") {
-			color.Yellow("  ! Warning: AI output might contain multiple files. Attempting to extract relevant code.")
+		if result.Summary != "" {
+			fmt.Println(cSubtle("  " + result.Summary))
+		}
+		newCode := string(content)
+		if result.Changed {
+			newCode = result.Content
 		}
 
 		diff := difflib.UnifiedDiff{
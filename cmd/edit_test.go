@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/broman0x/forgeai-cli/internal/ai/aitest"
+)
+
+const originalGo = "package main\n\nfunc main() {}\n"
+
+const editedGo = "package main\nimport \"fmt\"\nfunc main() { fmt.Println(\"hi\") }"
+
+func TestEditAppliesChangesWhenConfirmed(t *testing.T) {
+	dir := setupTest(t)
+	path := filepath.Join(dir, "main.go")
+	writeFile(t, path, originalGo)
+
+	fake := aitest.NewFakeProvider("```go\n" + editedGo + "\n```")
+	out := captureOutput(t, func() {
+		runEditLogic(fake, path, "print hi", answers("y"))
+	})
+
+	if got := readFile(t, path); got != editedGo {
+		t.Fatalf("file = %q, want %q", got, editedGo)
+	}
+	if !strings.Contains(out, "DIFF PREVIEW") {
+		t.Fatalf("diff was not shown:\n%s", out)
+	}
+
+	calls := fake.Calls()
+	if len(calls) != 1 {
+		t.Fatalf("calls = %d, want 1", len(calls))
+	}
+	if calls[0].System != editSystemPrompt {
+		t.Fatal("edit system prompt was not sent as the system prompt")
+	}
+	if !strings.Contains(calls[0].Prompt, `"print hi"`) || !strings.Contains(calls[0].Prompt, "func main() {}") {
+		t.Fatalf("prompt is missing the instruction or code:\n%s", calls[0].Prompt)
+	}
+}
+
+func TestEditDiscardsChangesWhenDeclined(t *testing.T) {
+	dir := setupTest(t)
+	path := filepath.Join(dir, "main.go")
+	writeFile(t, path, originalGo)
+
+	fake := aitest.NewFakeProvider(editedGo)
+	captureOutput(t, func() {
+		runEditLogic(fake, path, "print hi", answers("n"))
+	})
+
+	if got := readFile(t, path); got != originalGo {
+		t.Fatalf("file changed after declining: %q", got)
+	}
+}
+
+func TestEditCreatesNewFile(t *testing.T) {
+	dir := setupTest(t)
+	path := filepath.Join(dir, "hello.py")
+
+	fake := aitest.NewFakeProvider("print('hello')")
+	out := captureOutput(t, func() {
+		runEditLogic(fake, path, "create a hello world script", answers("y"))
+	})
+
+	if got := readFile(t, path); got != "print('hello')" {
+		t.Fatalf("file = %q", got)
+	}
+	if !strings.Contains(out, "NEW FILE PREVIEW") {
+		t.Fatalf("preview was not shown:\n%s", out)
+	}
+}
+
+func TestEditReportsProviderErrors(t *testing.T) {
+	dir := setupTest(t)
+	path := filepath.Join(dir, "main.go")
+	writeFile(t, path, originalGo)
+
+	fake := aitest.NewFakeProvider()
+	fake.Replies = []aitest.Reply{{Err: fmt.Errorf("quota exceeded")}}
+	out := captureOutput(t, func() {
+		runEditLogic(fake, path, "print hi", answers("y"))
+	})
+
+	if !strings.Contains(out, "quota exceeded") {
+		t.Fatalf("error was not reported:\n%s", out)
+	}
+	if got := readFile(t, path); got != originalGo {
+		t.Fatalf("file changed after an error: %q", got)
+	}
+}
+
+func TestAgentModeEditsOnlyFilesThatChange(t *testing.T) {
+	dir := setupTest(t)
+	writeFile(t, filepath.Join(dir, "app.js"), "var x = 1\n")
+	writeFile(t, filepath.Join(dir, "style.css"), "body { color: red; }\n")
+	writeFile(t, filepath.Join(dir, "notes.txt"), "not code\n")
+
+	fake := aitest.NewFakeProvider()
+	fake.Respond = func(prompt string) (string, error) {
+		if strings.Contains(prompt, `processing file "app.js"`) {
+			return `{"changed": true, "summary": "Use const.", "content": "const x = 1;\n"}`, nil
+		}
+		return "```json\n{\"changed\": false, \"summary\": \"No CSS work needed.\", \"content\": \"\"}\n```", nil
+	}
+
+	out := captureOutput(t, func() {
+		runEditLogic(fake, dir, "refactor everything", answers("y", "y"))
+	})
+
+	if got := readFile(t, filepath.Join(dir, "app.js")); got != "const x = 1;\n" {
+		t.Fatalf("app.js = %q", got)
+	}
+	if got := readFile(t, filepath.Join(dir, "style.css")); got != "body { color: red; }\n" {
+		t.Fatalf("style.css = %q", got)
+	}
+	if len(fake.Calls()) != 2 {
+		t.Fatalf("calls = %d, want one per code file", len(fake.Calls()))
+	}
+	if !strings.Contains(out, "PROJECT AGENT MODE") || !strings.Contains(out, "Modified:  1 files") {
+		t.Fatalf("unexpected agent output:\n%s", out)
+	}
+	if call := fake.Calls()[0]; call.Format == nil || call.Format.Name != "file_update" {
+		t.Fatalf("agent did not ask for structured output: %+v", call.Format)
+	}
+}
+
+func TestAgentModeStripsFencesFromContent(t *testing.T) {
+	dir := setupTest(t)
+	writeFile(t, filepath.Join(dir, "main.go"), "package main\n")
+
+	fake := aitest.NewFakeProvider(`{"changed": true, "summary": "Add main.", "content": "` + "```go" + `\npackage main\n\nfunc main() {}\n` + "```" + `\n"}`)
+	out := captureOutput(t, func() {
+		runEditLogic(fake, dir, "refactor everything", answers("y", "y"))
+	})
+
+	if got := readFile(t, filepath.Join(dir, "main.go")); strings.Contains(got, "```") || !strings.HasPrefix(got, "package main") || !strings.Contains(got, "func main() {}") {
+		t.Fatalf("main.go = %q\n%s", got, out)
+	}
+}
+
+func TestAgentModeKeepsFileWhenChangedReplyIsEmpty(t *testing.T) {
+	dir := setupTest(t)
+	writeFile(t, filepath.Join(dir, "app.js"), "var x = 1\n")
+
+	fake := aitest.NewFakeProvider(`{"changed": true, "summary": "Use const.", "content": "  \n"}`)
+	out := captureOutput(t, func() {
+		runEditLogic(fake, dir, "refactor everything", answers("y", "y"))
+	})
+
+	if got := readFile(t, filepath.Join(dir, "app.js")); got != "var x = 1\n" {
+		t.Fatalf("app.js = %q, want it untouched", got)
+	}
+	if !strings.Contains(out, "came back empty") || !strings.Contains(out, "Modified:  0 files") {
+		t.Fatalf("unexpected agent output:\n%s", out)
+	}
+}
+
+func TestAgentModeRetriesInvalidJSONOnce(t *testing.T) {
+	dir := setupTest(t)
+	writeFile(t, filepath.Join(dir, "app.js"), "var x = 1\n")
+
+	fake := aitest.NewFakeProvider(
+		"const x = 1;",
+		`{"changed": true, "summary": "Use const.", "content": "const x = 1;\n"}`)
+	captureOutput(t, func() {
+		runEditLogic(fake, dir, "refactor everything", answers("y", "y"))
+	})
+
+	calls := fake.Calls()
+	if len(calls) != 2 || !strings.Contains(calls[1].Prompt, "not valid") {
+		t.Fatalf("calls = %+v, want one corrective retry", calls)
+	}
+	if got := readFile(t, filepath.Join(dir, "app.js")); got != "const x = 1;\n" {
+		t.Fatalf("app.js = %q", got)
+	}
+}
+
+func TestAgentModeStopsWhenNotConfirmed(t *testing.T) {
+	dir := setupTest(t)
+	writeFile(t, filepath.Join(dir, "app.js"), "var x = 1\n")
+
+	fake := aitest.NewFakeProvider("const x = 1;\n")
+	captureOutput(t, func() {
+		runEditLogic(fake, dir, "refactor everything", answers("n"))
+	})
+
+	if len(fake.Calls()) != 0 {
+		t.Fatalf("agent called the provider after being stopped")
+	}
+}
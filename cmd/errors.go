@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/broman0x/forgeai-cli/internal/ai"
+	"github.com/broman0x/forgeai-cli/internal/lang"
+	"github.com/fatih/color"
+)
+
+// errorGuidance returns a localized hint for the typed provider errors, or ""
+// when there is nothing more useful to say than the error itself.
+func errorGuidance(err error) string {
+	var (
+		auth        *ai.AuthError
+		quota       *ai.QuotaError
+		rateLimit   *ai.RateLimitError
+		ctxLen      *ai.ContextLengthError
+		blocked     *ai.ContentBlockedError
+		timeout     *ai.TimeoutError
+		unavailable *ai.UnavailableError
+	)
+	switch {
+	case errors.As(err, &auth):
+		return lang.T("err_auth")
+	case errors.As(err, &quota):
+		return lang.T("err_quota")
+	case errors.As(err, &rateLimit):
+		return lang.T("err_rate_limit")
+	case errors.As(err, &ctxLen):
+		return lang.T("err_context_length")
+	case errors.As(err, &blocked):
+		return lang.T("err_content_blocked")
+	case errors.As(err, &timeout):
+		return lang.T("err_timeout")
+	case errors.As(err, &unavailable):
+		return lang.T("err_unavailable")
+	}
+	return ""
+}
+
+func printGuidance(err error) {
+	if hint := errorGuidance(err); hint != "" {
+		color.Yellow("  %s", hint)
+	}
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/broman0x/forgeai-cli/internal/ai/aitest"
+	"github.com/fatih/color"
+)
+
+// captureOutput runs fn with stdout and the color writer redirected, and
+// returns everything it printed.
+func captureOutput(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, colorOut := os.Stdout, color.Output
+	os.Stdout, color.Output = w, w
+
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+
+	defer func() {
+		os.Stdout, color.Output = stdout, colorOut
+	}()
+	fn()
+	w.Close()
+	return <-done
+}
+
+func answers(lines ...string) *bufio.Scanner {
+	return bufio.NewScanner(strings.NewReader(strings.Join(lines, "\n") + "\n"))
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func setupTest(t *testing.T) string {
+	t.Helper()
+	aitest.Isolate(t)
+	return t.TempDir()
+}
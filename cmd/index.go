@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/broman0x/forgeai-cli/internal/index"
+	"github.com/broman0x/forgeai-cli/internal/ui"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+const repoContextChunks = 6
+
+var indexCmd = &cobra.Command{
+	Use:   "index [directory]",
+	Short: "Build or update the semantic code index used by ask --repo",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		root := "."
+		if len(args) > 0 {
+			root = args[0]
+		}
+		if err := runIndex(root); err != nil {
+			color.Red("  Error: %v", err)
+			printGuidance(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(indexCmd)
+}
+
+func runIndex(root string) error {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return fmt.Errorf("not a directory: %s", root)
+	}
+
+	idx, err := index.Load(root)
+	if errors.Is(err, index.ErrNoIndex) {
+		idx = index.New(root)
+	} else if err != nil {
+		return err
+	}
+	emb, err := idx.OpenEmbedder()
+	if err != nil {
+		return err
+	}
+
+	cInfo := color.New(color.FgCyan).SprintFunc()
+	fmt.Println()
+	fmt.Printf("  %s %s\n", cInfo("Directory:"), root)
+	fmt.Printf("  %s %s\n", cInfo("Embeddings:"), emb.Name())
+	fmt.Println()
+
+	ctx, stop := commandContext("index")
+	defer stop()
+
+	bar := ui.NewProgressBar("Embedding")
+	stats, err := idx.Update(ctx, emb, scanDirectory(root), bar.Update)
+	bar.Done()
+
+	if isCancelled(err) {
+		color.Yellow("  Indexing cancelled; finished files were saved.")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	color.Green("  ✓ Indexed %d files (%d chunks): %d updated, %d unchanged, %d removed",
+		stats.Files, stats.Chunks, stats.Updated, stats.Unchanged, stats.Removed)
+	return nil
+}
+
+// repoContext retrieves the chunks of the index in the current directory
+// closest to question and lays them out as prompt context.
+func repoContext(question string) (string, error) {
+	root, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	idx, err := index.Load(root)
+	if err != nil {
+		return "", err
+	}
+	emb, err := idx.OpenEmbedder()
+	if err != nil {
+		return "", err
+	}
+
+	ctx, stop := commandContext("ask")
+	defer stop()
+	results, err := idx.Search(ctx, emb, question, repoContextChunks)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Relevant code from the repository:\n")
+	for _, r := range results {
+		fmt.Fprintf(&sb, "\n--- %s (lines %d-%d) ---\n%s\n", r.File, r.StartLine, r.EndLine, r.Text)
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+
+	"github.com/broman0x/forgeai-cli/internal/usage"
+)
+
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+
+	go func() {
+		select {
+		case <-sig:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(sig)
+		cancel()
+	}
+}
+
+func commandContext(command string) (context.Context, context.CancelFunc) {
+	ctx, stop := interruptContext()
+	return usage.WithCommand(ctx, command), stop
+}
+
+func isCancelled(err error) bool {
+	return errors.Is(err, context.Canceled)
+}
@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/broman0x/forgeai-cli/internal/ai"
+	"github.com/broman0x/forgeai-cli/internal/ui"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var modelsCmd = &cobra.Command{
+	Use:   "models",
+	Short: "List and manage local Ollama models",
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := ai.NewOllamaClient()
+		if err != nil {
+			color.Red("  Error: %v", err)
+			return
+		}
+		ctx, stop := commandContext("models")
+		defer stop()
+		listOllamaModels(ctx, client)
+	},
+}
+
+var modelsPullCmd = &cobra.Command{
+	Use:   "pull [model]",
+	Short: "Download an Ollama model",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := ai.NewOllamaClient()
+		if err != nil {
+			color.Red("  Error: %v", err)
+			return
+		}
+		ctx, stop := commandContext("models")
+		defer stop()
+		if err := pullOllamaModel(ctx, client, args[0]); err != nil {
+			color.Red("  Error: %v", err)
+		}
+	},
+}
+
+var modelsShowCmd = &cobra.Command{
+	Use:   "show [model]",
+	Short: "Show details of an Ollama model",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := ai.NewOllamaClient()
+		if err != nil {
+			color.Red("  Error: %v", err)
+			return
+		}
+		ctx, stop := commandContext("models")
+		defer stop()
+		showOllamaModel(ctx, client, args[0])
+	},
+}
+
+var modelsRmCmd = &cobra.Command{
+	Use:     "rm [model]",
+	Aliases: []string{"delete"},
+	Short:   "Delete an Ollama model",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := ai.NewOllamaClient()
+		if err != nil {
+			color.Red("  Error: %v", err)
+			return
+		}
+		if !confirm(nil, fmt.Sprintf("  Delete model '%s'? [y/N]", args[0])) {
+			color.Yellow("  Cancelled.")
+			return
+		}
+		ctx, stop := commandContext("models")
+		defer stop()
+		if err := client.DeleteModel(ctx, args[0]); err != nil {
+			color.Red("  Error: %v", err)
+			return
+		}
+		color.Green("  + Deleted %s", args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(modelsCmd)
+	modelsCmd.AddCommand(modelsPullCmd, modelsShowCmd, modelsRmCmd)
+}
+
+func listOllamaModels(ctx context.Context, client *ai.OllamaProvider) []ai.OllamaModel {
+	models, err := client.ListModels(ctx)
+	if err != nil {
+		color.Red("  Error: %v", err)
+		return nil
+	}
+
+	cTitle := color.New(color.FgHiCyan, color.Bold).SprintFunc()
+	cSubtle := color.New(color.FgHiBlack).SprintFunc()
+
+	fmt.Println()
+	fmt.Println(cTitle("  OLLAMA MODELS"))
+	fmt.Println(cSubtle("  ───────────────────────────────────────────"))
+
+	if len(models) == 0 {
+		color.Yellow("  No models installed. Try: forgeai models pull llama3")
+		fmt.Println()
+		return nil
+	}
+
+	sort.Slice(models, func(i, j int) bool { return models[i].Name < models[j].Name })
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  NAME\tSIZE\tPARAMS\tQUANT\tMODIFIED")
+	for _, m := range models {
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n",
+			m.Name,
+			ui.FormatBytes(m.Size),
+			m.Details.ParameterSize,
+			m.Details.QuantizationLevel,
+			m.ModifiedAt.Format("2006-01-02"))
+	}
+	w.Flush()
+	fmt.Println()
+
+	return models
+}
+
+func pullOllamaModel(ctx context.Context, client *ai.OllamaProvider, name string) error {
+	color.Cyan("  Pulling %s...", name)
+
+	bar := ui.NewProgressBar("")
+	err := client.PullModel(ctx, name, func(p ai.PullProgress) {
+		bar.SetLabel(p.Status)
+		bar.Update(p.Completed, p.Total)
+	})
+	bar.Done()
+
+	if isCancelled(err) {
+		return fmt.Errorf("pull cancelled")
+	}
+	if err != nil {
+		return err
+	}
+
+	color.Green("  + %s is ready", name)
+	return nil
+}
+
+func showOllamaModel(ctx context.Context, client *ai.OllamaProvider, name string) {
+	details, err := client.ShowModel(ctx, name)
+	if err != nil {
+		color.Red("  Error: %v", err)
+		return
+	}
+
+	cTitle := color.New(color.FgHiCyan, color.Bold).SprintFunc()
+	cLabel := color.New(color.FgHiBlack).SprintFunc()
+	cSubtle := color.New(color.FgHiBlack).SprintFunc()
+
+	fmt.Println()
+	fmt.Println(cTitle("  " + strings.ToUpper(name)))
+	fmt.Println(cSubtle("  ───────────────────────────────────────────"))
+
+	keys := make([]string, 0, len(details.Details))
+	for k := range details.Details {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Printf("   • %s %v\n", cLabel(k+":"), details.Details[k])
+	}
+
+	for k, v := range details.ModelInfo {
+		if strings.HasSuffix(k, ".context_length") {
+			fmt.Printf("   • %s %v\n", cLabel("context_length:"), v)
+		}
+	}
+
+	if details.Parameters != "" {
+		fmt.Println()
+		fmt.Println(cLabel("  Parameters:"))
+		for _, line := range strings.Split(strings.TrimSpace(details.Parameters), "\n") {
+			fmt.Printf("    %s\n", line)
+		}
+	}
+
+	if details.License != "" {
+		license := strings.SplitN(strings.TrimSpace(details.License), "\n", 2)[0]
+		fmt.Println()
+		fmt.Printf("  %s %s\n", cLabel("License:"), license)
+	}
+	fmt.Println()
+}
+
+func chooseOllamaModel(scanner *bufio.Scanner) (string, bool) {
+	client, err := ai.NewOllamaClient()
+	if err != nil {
+		color.Red("  Error: %v", err)
+		return "", false
+	}
+
+	ctx, stop := commandContext("models")
+	models, err := client.ListModels(ctx)
+	stop()
+	if err != nil {
+		color.Red("  Error: %v", err)
+		return "", false
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].Name < models[j].Name })
+
+	ui.PrintHeader("SELECT OLLAMA MODEL")
+	if len(models) == 0 {
+		color.Yellow("  No models installed yet.")
+	}
+
+	names := make([]string, len(models))
+	for i, m := range models {
+		names[i] = m.Name
+	}
+	defaultModel := "llama3"
+	if len(names) > 0 {
+		defaultModel = names[0]
+	}
+
+	input, ok := pickModel(scanner, names, defaultModel)
+	if !ok {
+		return "", false
+	}
+
+	for _, m := range models {
+		if ai.OllamaModelMatches(m.Name, input) {
+			return input, true
+		}
+	}
+
+	if !offerOllamaPull(scanner, input) {
+		return "", false
+	}
+	return input, true
+}
+
+func offerOllamaPull(scanner *bufio.Scanner, name string) bool {
+	color.Yellow("  Model '%s' is not installed.", name)
+	if !confirm(scanner, "  Pull it now? [y/N]") {
+		return false
+	}
+
+	client, err := ai.NewOllamaClient()
+	if err != nil {
+		color.Red("  Error: %v", err)
+		return false
+	}
+
+	ctx, stop := commandContext("models")
+	defer stop()
+	if err := pullOllamaModel(ctx, client, name); err != nil {
+		color.Red("  Error: %v", err)
+		return false
+	}
+	return true
+}
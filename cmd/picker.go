@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/broman0x/forgeai-cli/internal/ai"
+	"github.com/broman0x/forgeai-cli/internal/ui"
+	"github.com/fatih/color"
+)
+
+const pickerPageSize = 15
+
+func chooseCloudModel(scanner *bufio.Scanner, providerType, title string) (string, bool) {
+	spinner := ui.NewSpinner("Fetching models")
+	spinner.Start()
+	ctx, stop := commandContext("models")
+	catalog := ai.DiscoverModels(ctx, providerType)
+	stop()
+	spinner.Stop()
+
+	ui.PrintHeader(title)
+	if catalog.Offline && catalog.FetchedAt.IsZero() {
+		color.Yellow("  Could not fetch the live model list, showing built-in choices.")
+	} else if catalog.Offline {
+		color.Yellow("  Could not refresh the model list, showing results from %s.", catalog.FetchedAt.Format("2006-01-02 15:04"))
+	}
+
+	defaultModel := ""
+	if len(catalog.Models) > 0 {
+		defaultModel = catalog.Models[0]
+	}
+	return pickModel(scanner, catalog.Models, defaultModel)
+}
+
+func pickModel(scanner *bufio.Scanner, models []string, defaultModel string) (string, bool) {
+	cSubtle := color.New(color.FgHiBlack).SprintFunc()
+	shown := models
+
+	for {
+		limit := len(shown)
+		if limit > pickerPageSize {
+			limit = pickerPageSize
+		}
+		for i := 0; i < limit; i++ {
+			fmt.Printf("  %2d. %s\n", i+1, shown[i])
+		}
+		if len(shown) > limit {
+			fmt.Println(cSubtle(fmt.Sprintf("      ... %d more, type part of a name to filter", len(shown)-limit)))
+		}
+
+		if defaultModel != "" {
+			fmt.Printf("\n  Number, filter or model name [%s]: ", defaultModel)
+		} else {
+			fmt.Print("\n  Number, filter or model name: ")
+		}
+		if !scanner.Scan() {
+			return "", false
+		}
+		input := strings.TrimSpace(scanner.Text())
+
+		if input == "" {
+			return defaultModel, defaultModel != ""
+		}
+
+		if n, err := strconv.Atoi(input); err == nil {
+			if n >= 1 && n <= limit {
+				return shown[n-1], true
+			}
+			color.Yellow("  Invalid selection\n")
+			continue
+		}
+
+		for _, m := range models {
+			if m == input {
+				return m, true
+			}
+		}
+
+		// Only an exact name is taken without asking: "llama3" must not
+		// quietly become the one installed "llama3.2", and a typo must not
+		// quietly become a custom model.
+		filtered := filterModels(models, input)
+		if len(filtered) == 1 && confirm(scanner, fmt.Sprintf("  Did you mean '%s'? [y/N]", filtered[0])) {
+			return filtered[0], true
+		}
+		if len(filtered) <= 1 {
+			if len(filtered) == 0 {
+				color.Yellow("  No listed model matches '%s'.", input)
+			}
+			if confirm(scanner, fmt.Sprintf("  Use '%s' as a custom model name? [y/N]", input)) {
+				return input, true
+			}
+			shown = models
+			fmt.Println()
+			continue
+		}
+		shown = filtered
+		fmt.Println()
+	}
+}
+
+func filterModels(models []string, query string) []string {
+	query = strings.ToLower(query)
+	var out []string
+	for _, m := range models {
+		if strings.Contains(strings.ToLower(m), query) {
+			out = append(out, m)
+		}
+	}
+	return out
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import "testing"
+
+func TestPickModelOnlyTakesExactNamesWithoutAsking(t *testing.T) {
+	installed := []string{"llama3.2:latest", "mistral-nemo:latest", "qwen2.5:7b"}
+	tests := []struct {
+		name   string
+		input  []string
+		want   string
+		wantOK bool
+	}{
+		{"exact name", []string{"qwen2.5:7b"}, "qwen2.5:7b", true},
+		{"number", []string{"2"}, "mistral-nemo:latest", true},
+		{"single match confirmed", []string{"llama3", "y"}, "llama3.2:latest", true},
+		{"single match declined keeps the typed name", []string{"llama3", "n", "y"}, "llama3", true},
+		{"typo confirmed as custom", []string{"mistrl", "y"}, "mistrl", true},
+		{"typo declined asks again", []string{"mistrl", "n", "1"}, "llama3.2:latest", true},
+		{"input ends", []string{"mistrl"}, "", false},
+	}
+	for _, tt := range tests {
+		var got string
+		var ok bool
+		captureOutput(t, func() {
+			got, ok = pickModel(answers(tt.input...), installed, "")
+		})
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("%s: got %q %v, want %q %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
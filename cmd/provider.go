@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/broman0x/forgeai-cli/internal/ai"
+	"github.com/broman0x/forgeai-cli/internal/lang"
+	"github.com/fatih/color"
+	"github.com/spf13/pflag"
+)
+
+func newProvider() (ai.Provider, error) {
+	prov, err := ai.NewProvider()
+	if err != nil {
+		return nil, err
+	}
+	if chain, ok := prov.(*ai.ChainProvider); ok {
+		chain.OnFailover = func(from, to ai.Provider, err error) {
+			fmt.Print("\r\033[K")
+			color.Yellow("  ⚠ %s failed: %v", from.Name(), err)
+			color.Yellow("  ↪ Switching to %s", to.Name())
+		}
+	}
+	return ai.WithHistoryBudget(prov), nil
+}
+
+// generationFlags is set in init; reaching it through rootCmd here would
+// make rootCmd's initializer depend on itself.
+var generationFlags *pflag.FlagSet
+
+// generationOptions resolves the config for command, then applies any
+// sampling and reasoning flags given on the command line.
+func generationOptions(command string) ai.GenerationOptions {
+	opts := ai.OptionsFor(command)
+	flags := generationFlags
+	if flags.Changed("temperature") {
+		opts.Temperature = ai.Float(flagTemperature)
+	}
+	if flags.Changed("top-p") {
+		opts.TopP = ai.Float(flagTopP)
+	}
+	if flags.Changed("max-tokens") {
+		opts.MaxTokens = flagMaxTokens
+	}
+	if flags.Changed("stop") {
+		opts.Stop = flagStop
+	}
+	if flags.Changed("reasoning-effort") || flags.Changed("thinking-budget") {
+		opts.ReasoningEffort = flagEffort
+		opts.ThinkingBudget = flagBudget
+	}
+	if opts.ReasoningEffort != "" && !ai.ValidReasoningEffort(opts.ReasoningEffort) {
+		color.New(color.FgYellow).Fprintf(os.Stderr, "  Ignoring reasoning effort %q: use low, medium or high\n", opts.ReasoningEffort)
+		opts.ReasoningEffort = ""
+	}
+	return opts
+}
+
+func withResponseCache(prov ai.Provider) ai.Provider {
+	if noCache {
+		return prov
+	}
+	return ai.WithCache(prov, ai.NewResponseCache())
+}
+
+// checkPromptFits refuses a prompt larger than the model's known context
+// window, and warns on w when it is close to the limit or the window is only
+// a guess.
+func checkPromptFits(w io.Writer, prov ai.Provider, prompt string, images int) error {
+	fit, err := ai.CheckPrompt(prov.ModelName(), prompt, images)
+	if err != nil {
+		return err
+	}
+	switch {
+	case !fit.Known && fit.Tokens > fit.Window:
+		fmt.Fprintln(w, color.YellowString("  ⚠ "+lang.T("prompt_unknown_window"), fit.Tokens, prov.ModelName()))
+	case fit.Known && fit.Tight():
+		fmt.Fprintln(w, color.YellowString("  ⚠ "+lang.T("prompt_near_limit"), fit.Tokens, fit.Window))
+	}
+	return nil
+}
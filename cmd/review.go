@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -13,6 +15,8 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var reviewJSON bool
+
 var reviewCmd = &cobra.Command{
 	Use:   "review [file]",
 	Short: "Review a source code file",
@@ -21,13 +25,104 @@ var reviewCmd = &cobra.Command{
 			cmd.Help()
 			return
 		}
-		prov, _ := ai.NewProvider()
+		prov, err := newProvider()
+		if err != nil {
+			color.Red("Error: %v", err)
+			return
+		}
+		if reviewJSON {
+			if err := runReviewJSON(prov, args[0], os.Stdout); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				if hint := errorGuidance(err); hint != "" {
+					fmt.Fprintln(os.Stderr, hint)
+				}
+				os.Exit(1)
+			}
+			return
+		}
 		runReviewLogic(prov, args[0])
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(reviewCmd)
+	reviewCmd.Flags().BoolVar(&reviewJSON, "json", false, "print the review as JSON for scripts and CI")
+}
+
+type reviewReport struct {
+	File    string        `json:"file"`
+	Summary string        `json:"summary"`
+	Score   int           `json:"score"`
+	Issues  []reviewIssue `json:"issues"`
+}
+
+type reviewIssue struct {
+	Severity   string `json:"severity"`
+	Category   string `json:"category"`
+	Line       int    `json:"line"`
+	Title      string `json:"title"`
+	Detail     string `json:"detail"`
+	Suggestion string `json:"suggestion"`
+}
+
+var reviewSchema = ai.Schema{
+	Name:        "code_review",
+	Description: "A structured code review of one file.",
+	Schema: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"summary": map[string]interface{}{"type": "string", "description": "Two or three sentences on overall quality."},
+			"score":   map[string]interface{}{"type": "integer", "description": "Quality score from 1 to 10."},
+			"issues": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"severity":   map[string]interface{}{"type": "string", "enum": []string{"critical", "high", "medium", "low"}},
+						"category":   map[string]interface{}{"type": "string", "enum": []string{"bug", "security", "performance", "design", "style", "testing"}},
+						"line":       map[string]interface{}{"type": "integer", "description": "1-based line number, 0 if not tied to a line."},
+						"title":      map[string]interface{}{"type": "string"},
+						"detail":     map[string]interface{}{"type": "string"},
+						"suggestion": map[string]interface{}{"type": "string"},
+					},
+					"required": []string{"severity", "category", "line", "title", "detail", "suggestion"},
+				},
+			},
+		},
+		"required": []string{"summary", "score", "issues"},
+	},
+}
+
+func runReviewJSON(prov ai.Provider, filePath string, out io.Writer) error {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	prov = withResponseCache(prov)
+	prov.SetSystemPrompt("You are a senior software engineer doing a production code review. Report concrete, actionable issues only, most severe first.")
+	prov.SetOptions(generationOptions("review"))
+
+	ctx, stop := commandContext("review")
+	defer stop()
+
+	var report reviewReport
+	lang := detectLanguageForReview(filepath.Ext(filePath))
+	prompt := fmt.Sprintf("Review this %s file.\nFile: %s\nCode:\n%s", lang, filePath, string(content))
+	if err := checkPromptFits(os.Stderr, prov, prompt, 0); err != nil {
+		return err
+	}
+	if err := ai.SendJSON(ctx, prov, prompt, reviewSchema, &report); err != nil {
+		return err
+	}
+	report.File = filePath
+	if report.Issues == nil {
+		report.Issues = []reviewIssue{}
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
 }
 
 func StartReviewModeInteractive(scanner *bufio.Scanner, prov ai.Provider) {
@@ -82,14 +177,13 @@ func runReviewLogicWithLang(prov ai.Provider, filePath string, language string)
 		return
 	}
 
+	prov = withResponseCache(prov)
+
 	ext := filepath.Ext(filePath)
 	lang := detectLanguageForReview(ext)
 
 	cInfo := color.New(color.FgCyan).SprintFunc()
 	cSubtle := color.New(color.FgHiBlack).SprintFunc()
-	cSection := color.New(color.FgCyan, color.Bold).SprintFunc()
-	cBullet := color.New(color.FgMagenta).SprintFunc()
-	cText := color.New(color.FgWhite).SprintFunc()
 
 	fmt.Println()
 	fmt.Printf("  %s %s\n", cInfo("File:"), filePath)
@@ -97,14 +191,11 @@ func runReviewLogicWithLang(prov ai.Provider, filePath string, language string)
 	fmt.Printf("  %s %s\n", cInfo("Engine:"), prov.Name())
 	fmt.Println()
 
-	spinner := ui.NewSpinner("Analyzing code")
-	spinner.Start()
-
-	var prompt string
+	var systemPrompt, prompt string
 	if language == "indonesian" {
-		prompt = fmt.Sprintf(`Kamu adalah SENIOR SOFTWARE ARCHITECT dengan keahlian mendalam di %s, code review, dan system design.
+		systemPrompt = fmt.Sprintf(`Kamu adalah SENIOR SOFTWARE ARCHITECT dengan keahlian mendalam di %s, code review, dan system design.
 
-TASK: Lakukan code review profesional yang komprehensif untuk kode ini.
+TASK: Lakukan code review profesional yang komprehensif untuk kode yang dikirim user.
 
 ANALISA MENDALAM:
 1. ARCHITECTURE & DESIGN PATTERNS: Evaluasi struktur kode, design patterns yang digunakan/dibutuhkan
@@ -145,15 +236,12 @@ Gambaran umum kualitas kode dalam 2-3 kalimat
 - Refactoring opportunities
 
 ## Skor Kualitas 📊
-X/10 - Penjelasan detail berdasarkan Production Readiness, Security, Performance, Maintainability
-
-File: %s
-Kode:
-%s`, lang, filePath, string(content))
+X/10 - Penjelasan detail berdasarkan Production Readiness, Security, Performance, Maintainability`, lang)
+		prompt = fmt.Sprintf("File: %s\nKode:\n%s", filePath, string(content))
 	} else {
-		prompt = fmt.Sprintf(`You are a SENIOR SOFTWARE ARCHITECT with deep expertise in %s, code review, and system design.
+		systemPrompt = fmt.Sprintf(`You are a SENIOR SOFTWARE ARCHITECT with deep expertise in %s, code review, and system design.
 
-TASK: Perform a comprehensive professional code review.
+TASK: Perform a comprehensive professional code review of the code the user sends.
 
 IN-DEPTH ANALYSIS:
 1. ARCHITECTURE & DESIGN PATTERNS: Evaluate code structure, patterns used/needed
@@ -194,109 +282,41 @@ Overall code quality in 2-3 sentences
 - Refactoring opportunities
 
 ## Quality Score 📊
-X/10 - Detailed explanation based on Production Readiness, Security, Performance, Maintainability
-
-File: %s
-Code:
-%s`, lang, filePath, string(content))
+X/10 - Detailed explanation based on Production Readiness, Security, Performance, Maintainability`, lang)
+		prompt = fmt.Sprintf("File: %s\nCode:\n%s", filePath, string(content))
 	}
 
-	resp, err := prov.Send(prompt)
-	spinner.Stop()
-
-	if err != nil {
+	if err := checkPromptFits(os.Stdout, prov, prompt, 0); err != nil {
 		color.Red("  Error: %v", err)
+		printGuidance(err)
 		return
 	}
 
-	fmt.Println(cSubtle("  ───────────────────────────────────────────"))
-	fmt.Println()
-
-	lines := strings.Split(resp, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
+	prov.SetSystemPrompt(systemPrompt)
+	prov.SetOptions(generationOptions("review"))
 
-		if line == "" {
-			continue
-		}
+	ctx, stop := commandContext("review")
+	defer stop()
 
-		if strings.HasPrefix(line, "```") {
-			continue
-		}
+	_, err = streamReply(ctx, prov, prompt, "Analyzing code", func() {
+		fmt.Println(cSubtle("  ───────────────────────────────────────────"))
+		fmt.Println()
+	})
 
-		if strings.HasPrefix(line, "## ") {
-			section := strings.TrimPrefix(line, "## ")
-			fmt.Printf("\n  %s\n", cSection(strings.ToUpper(section)))
-		} else if strings.HasPrefix(line, "# ") {
-			section := strings.TrimPrefix(line, "# ")
-			fmt.Printf("\n  %s\n", cSection(strings.ToUpper(section)))
-		} else if strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ") {
-			item := strings.TrimPrefix(line, "- ")
-			item = strings.TrimPrefix(item, "* ")
-			item = cleanText(item)
-
-			wrapped := wrapText(item, 65)
-			for i, wrappedLine := range wrapped {
-				if i == 0 {
-					fmt.Printf("  %s %s\n", cBullet(">"), cText(wrappedLine))
-				} else {
-					fmt.Printf("    %s\n", cText(wrappedLine))
-				}
-			}
-		} else {
-			cleaned := cleanText(line)
-			wrapped := wrapText(cleaned, 70)
-			for _, wrappedLine := range wrapped {
-				fmt.Printf("  %s\n", cText(wrappedLine))
-			}
-		}
+	if isCancelled(err) {
+		color.Yellow("\n  Review cancelled")
+		return
+	}
+	if err != nil {
+		color.Red("  Error: %v", err)
+		printGuidance(err)
+		return
 	}
 
 	fmt.Println()
 	fmt.Println(cSubtle("  ───────────────────────────────────────────"))
 }
 
-func cleanText(text string) string {
-	text = strings.ReplaceAll(text, "**", "")
-	text = strings.ReplaceAll(text, "__", "")
-	text = strings.ReplaceAll(text, "`", "")
-	text = strings.ReplaceAll(text, "────────────────────", "")
-	return strings.TrimSpace(text)
-}
-
-func wrapText(text string, maxWidth int) []string {
-	if len(text) <= maxWidth {
-		return []string{text}
-	}
-
-	var lines []string
-	words := strings.Fields(text)
-	currentLine := ""
-
-	for _, word := range words {
-		testLine := currentLine
-		if testLine != "" {
-			testLine += " "
-		}
-		testLine += word
-
-		if len(testLine) <= maxWidth {
-			currentLine = testLine
-		} else {
-			if currentLine != "" {
-				lines = append(lines, currentLine)
-			}
-			currentLine = word
-		}
-	}
-
-	if currentLine != "" {
-		lines = append(lines, currentLine)
-	}
-
-	return lines
-}
-
 func detectLanguageForReview(ext string) string {
 	langMap := map[string]string{
 		".js":    "JavaScript",
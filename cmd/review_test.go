@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/broman0x/forgeai-cli/internal/ai/aitest"
+)
+
+const reviewReply = "## Executive Summary\nSmall and **correct**.\n\n## Quality Score 📊\n9/10"
+
+func TestReviewStreamsRenderedReview(t *testing.T) {
+	dir := setupTest(t)
+	path := filepath.Join(dir, "main.go")
+	writeFile(t, path, originalGo)
+
+	fake := aitest.NewFakeProvider(reviewReply)
+	out := captureOutput(t, func() {
+		runReviewLogicWithLang(fake, path, "english")
+	})
+
+	calls := fake.Calls()
+	if len(calls) != 1 || !calls[0].Stream {
+		t.Fatalf("calls = %+v, want one streamed call", calls)
+	}
+	if !strings.Contains(calls[0].System, "SENIOR SOFTWARE ARCHITECT") || !strings.Contains(calls[0].System, "expertise in Go") {
+		t.Fatalf("unexpected system prompt:\n%s", calls[0].System)
+	}
+	if calls[0].Prompt != "File: "+path+"\nCode:\n"+originalGo {
+		t.Fatalf("unexpected prompt:\n%s", calls[0].Prompt)
+	}
+	for _, want := range []string{"▊ Executive Summary", "Small and correct.", "9/10"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("output is missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestReviewUsesIndonesianPrompt(t *testing.T) {
+	dir := setupTest(t)
+	path := filepath.Join(dir, "app.py")
+	writeFile(t, path, "print('hi')\n")
+
+	fake := aitest.NewFakeProvider(reviewReply)
+	captureOutput(t, func() {
+		runReviewLogicWithLang(fake, path, "indonesian")
+	})
+
+	calls := fake.Calls()
+	if len(calls) != 1 || !strings.Contains(calls[0].System, "Ringkasan Eksekutif") || !strings.HasPrefix(calls[0].Prompt, "File: ") {
+		t.Fatalf("unexpected call: %+v", calls)
+	}
+}
+
+func TestReviewOfUnchangedFileIsServedFromCache(t *testing.T) {
+	dir := setupTest(t)
+	path := filepath.Join(dir, "main.go")
+	writeFile(t, path, originalGo)
+
+	fake := aitest.NewFakeProvider()
+	fake.Respond = func(string) (string, error) { return reviewReply, nil }
+	for i := 0; i < 2; i++ {
+		fake.Reset()
+		out := captureOutput(t, func() {
+			runReviewLogicWithLang(fake, path, "english")
+		})
+		if !strings.Contains(out, "9/10") {
+			t.Fatalf("run %d did not show the review:\n%s", i+1, out)
+		}
+	}
+	if len(fake.Calls()) != 1 {
+		t.Fatalf("calls = %d, want the second review to hit the cache", len(fake.Calls()))
+	}
+
+	writeFile(t, path, originalGo+"// changed\n")
+	fake.Reset()
+	captureOutput(t, func() {
+		runReviewLogicWithLang(fake, path, "english")
+	})
+	if len(fake.Calls()) != 2 {
+		t.Fatalf("calls = %d, a changed file must not hit the cache", len(fake.Calls()))
+	}
+}
+
+func TestReviewJSONPrintsValidatedReport(t *testing.T) {
+	dir := setupTest(t)
+	path := filepath.Join(dir, "main.go")
+	writeFile(t, path, originalGo)
+
+	fake := aitest.NewFakeProvider(
+		`{"summary": "Fine.", "score": 8, "issues": [{"severity": "urgent", "category": "bug", "line": 3, "title": "t", "detail": "d", "suggestion": "s"}]}`,
+		`{"summary": "Fine.", "score": 8, "issues": [{"severity": "high", "category": "bug", "line": 3, "title": "t", "detail": "d", "suggestion": "s"}]}`)
+
+	var buf strings.Builder
+	if err := runReviewJSON(fake, path, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var report reviewReport
+	if err := json.Unmarshal([]byte(buf.String()), &report); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, buf.String())
+	}
+	if report.File != path || len(report.Issues) != 1 || report.Issues[0].Severity != "high" {
+		t.Fatalf("report = %+v", report)
+	}
+	if calls := fake.Calls(); len(calls) != 2 || !strings.Contains(calls[1].Prompt, "severity must be one of") {
+		t.Fatalf("invalid enum was not sent back for correction: %+v", calls)
+	}
+}
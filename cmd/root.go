@@ -2,10 +2,12 @@ package cmd
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -23,6 +25,13 @@ const Version = "1.0.1"
 var (
 	cfgFile         string
 	noBanner        bool
+	noCache         bool
+	flagTemperature float64
+	flagTopP        float64
+	flagMaxTokens   int
+	flagStop        []string
+	flagEffort      string
+	flagBudget      int
 	doInstall       bool
 	doUninstall     bool
 	showVersion     bool
@@ -61,6 +70,14 @@ func init() {
 	cobra.OnInitialize(initConfig)
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file")
 	rootCmd.PersistentFlags().BoolVar(&noBanner, "no-banner", false, "disable banner")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "bypass the on-disk response cache")
+	rootCmd.PersistentFlags().Float64Var(&flagTemperature, "temperature", 0, "sampling temperature (overrides config)")
+	rootCmd.PersistentFlags().Float64Var(&flagTopP, "top-p", 0, "nucleus sampling top_p (overrides config)")
+	rootCmd.PersistentFlags().IntVar(&flagMaxTokens, "max-tokens", 0, "maximum output tokens (overrides config)")
+	rootCmd.PersistentFlags().StringArrayVar(&flagStop, "stop", nil, "stop sequence, repeatable (overrides config)")
+	rootCmd.PersistentFlags().StringVar(&flagEffort, "reasoning-effort", "", "reasoning effort for thinking models: low, medium or high (overrides config)")
+	rootCmd.PersistentFlags().IntVar(&flagBudget, "thinking-budget", 0, "thinking budget in tokens for thinking models (overrides config)")
+	generationFlags = rootCmd.PersistentFlags()
 	rootCmd.Flags().BoolVarP(&showVersion, "version", "v", false, "show version information")
 	rootCmd.Flags().BoolVar(&doInstall, "install", false, "install forge to PATH")
 	rootCmd.Flags().BoolVar(&doUninstall, "uninstall", false, "uninstall forge from PATH")
@@ -108,13 +125,19 @@ func runMainMenu() error {
 	ui.ShowStartupBanner()
 
 	var err error
-	currentProvider, err = ai.NewProvider()
+	currentProvider, err = newProvider()
+
+	scanner := bufio.NewScanner(os.Stdin)
+
+	var missing *ai.ModelNotFoundError
+	if errors.As(err, &missing) && offerOllamaPull(scanner, missing.Model) {
+		currentProvider, err = newProvider()
+	}
 
 	if err != nil {
 		return runSetupWizard(err)
 	}
 
-	scanner := bufio.NewScanner(os.Stdin)
 	cActive := color.New(color.BgCyan, color.FgBlack, color.Bold).SprintFunc()
 
 	for {
@@ -162,7 +185,6 @@ func runMainMenu() error {
 			ui.ShowStartupBanner()
 		}
 	}
-	return nil
 }
 
 func runFirstTimeSetup() error {
@@ -269,6 +291,7 @@ func runSetupWizard(originalErr error) error {
 	fmt.Println("  2. Google Gemini (Free tier available)")
 	fmt.Println("  3. OpenAI ChatGPT (Paid)")
 	fmt.Println("  4. Anthropic Claude (Paid)")
+	fmt.Println("  5. OpenAI-compatible server (LM Studio, vLLM, OpenRouter, ...)")
 	fmt.Println("  0. Exit")
 
 	fmt.Print("\n  Select > ")
@@ -356,11 +379,102 @@ func runSetupWizard(originalErr error) error {
 			fmt.Print("\033[H\033[2J")
 			return runMainMenu()
 		}
+
+	case "5":
+		model, ok := configureOpenAICompatible(scanner)
+		if !ok {
+			return fmt.Errorf("setup aborted")
+		}
+		if err := config.SaveLastModel("openai-compatible", model); err != nil {
+			color.Red("  Failed to save configuration: %v", err)
+			return err
+		}
+
+		color.Green("  Configuration saved! Restarting...")
+		time.Sleep(1 * time.Second)
+		fmt.Print("\033[H\033[2J")
+		return runMainMenu()
 	}
 
 	return nil
 }
 
+func configureOpenAICompatible(scanner *bufio.Scanner) (string, bool) {
+	current := config.Load().OpenAICompatible
+
+	ui.PrintHeader("OPENAI-COMPATIBLE SERVER")
+	fmt.Println("  Examples: http://localhost:1234/v1 (LM Studio), http://localhost:8000/v1 (vLLM),")
+	fmt.Println("            http://localhost:8080/v1 (llama.cpp), https://openrouter.ai/api/v1")
+	fmt.Println()
+
+	prompt := func(label, def string) string {
+		if def != "" {
+			fmt.Printf("  %s [%s]: ", label, def)
+		} else {
+			fmt.Printf("  %s: ", label)
+		}
+		if !scanner.Scan() {
+			return def
+		}
+		value := strings.TrimSpace(scanner.Text())
+		if value == "" {
+			return def
+		}
+		return value
+	}
+
+	baseURL := prompt("Base URL", current.BaseURL)
+	if baseURL == "" {
+		color.Red("  Base URL is required.")
+		return "", false
+	}
+
+	model := prompt("Model name", current.Model)
+	if model == "" {
+		color.Red("  Model name is required.")
+		return "", false
+	}
+
+	fmt.Print("  API key (optional, Enter to keep current): ")
+	scanner.Scan()
+	if apiKey := strings.TrimSpace(scanner.Text()); apiKey != "" {
+		if err := config.SaveAPIKey("OPENAI_COMPATIBLE_API_KEY", apiKey); err != nil {
+			color.Red("  Failed to save key: %v", err)
+			return "", false
+		}
+		godotenv.Overload()
+	}
+
+	fmt.Print("  Extra headers (optional, e.g. 'HTTP-Referer: https://x; X-Team: dev'): ")
+	scanner.Scan()
+	var headers map[string]string
+	if raw := strings.TrimSpace(scanner.Text()); raw != "" {
+		headers = parseHeaderList(raw)
+	}
+
+	if err := config.SaveOpenAICompatible(baseURL, model, headers); err != nil {
+		color.Red("  Failed to save configuration: %v", err)
+		return "", false
+	}
+
+	return model, true
+}
+
+func parseHeaderList(raw string) map[string]string {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(raw, ";") {
+		name, value, ok := strings.Cut(pair, ":")
+		if !ok {
+			continue
+		}
+		name = strings.TrimSpace(name)
+		if name != "" {
+			headers[name] = strings.TrimSpace(value)
+		}
+	}
+	return headers
+}
+
 func startChatMode(scanner *bufio.Scanner) {
 	fmt.Print("\033[H\033[2J")
 	ui.ShowStartupBanner()
@@ -369,29 +483,33 @@ func startChatMode(scanner *bufio.Scanner) {
 	cSubtle := color.New(color.FgHiBlack).SprintFunc()
 	fmt.Println()
 	fmt.Printf("  %s\n", cHeader("━━━ CHAT MODE ━━━"))
-	fmt.Printf("  %s\n", cSubtle("Type 'exit' to return • 'clear' to reset screen"))
+	fmt.Printf("  %s\n", cSubtle("Type 'exit' to return • 'clear' to reset screen • '/image <path>' to attach • '/thinking' to show reasoning"))
 	fmt.Println()
 
 	cAI := color.New(color.FgHiCyan, color.Bold).SprintFunc()
 	cPrompt := color.New(color.FgCyan).SprintFunc()
 
-	mdRenderer := ui.NewMarkdownRenderer()
-
 	var systemPrompt string
 	if lang.GetLanguage() == "id" {
 		systemPrompt = `Anda adalah Forge AI, asisten coding profesional yang dikembangkan oleh bromanprjkt. 
 Ketika ditanya siapa Anda, jawab: "Saya adalah Forge AI, asisten coding cerdas Anda yang dirancang untuk membantu code review, editing, dan tugas pengembangan."
 Ketika ditanya siapa yang membuat Anda, jawab: "Saya dikembangkan oleh bromanprjkt, developer yang passionate tentang AI-powered development tools."
-Selalu profesional, membantu, dan ringkas dalam respons Anda. Gunakan Bahasa Indonesia untuk semua respons.`
+Selalu profesional, membantu, dan ringkas dalam respons Anda. Gunakan Bahasa Indonesia untuk semua respons.
+Anda dapat membaca proyek pengguna di direktori saat ini dengan tool read-only yang tersedia. Gunakan tool tersebut daripada menebak isi file.`
 	} else {
 		systemPrompt = `You are Forge AI, a professional coding assistant developed by bromanprjkt. 
 When asked who you are, respond: "I am Forge AI, your intelligent coding assistant designed to help with code review, editing, and development tasks."
 When asked who created you, respond: "I was developed by bromanprjkt, a skilled developer passionate about AI-powered development tools."
-Always be professional, helpful, and concise in your responses.`
+Always be professional, helpful, and concise in your responses.
+You can read the user's project in the current directory with the read-only tools provided. Use them instead of guessing what files contain.`
 	}
 
-	currentProvider.Send(systemPrompt)
+	currentProvider.SetSystemPrompt(systemPrompt)
+	currentProvider.SetTools(chatTools())
+	currentProvider.SetOptions(generationOptions("chat"))
+	defer currentProvider.SetTools(nil)
 
+	var attached []ai.Image
 	for {
 		fmt.Printf("\n  %s ", cPrompt("You >"))
 
@@ -413,26 +531,58 @@ Always be professional, helpful, and concise in your responses.`
 			ui.ShowStartupBanner()
 			fmt.Println()
 			fmt.Printf("  %s\n", cHeader("━━━ CHAT MODE ━━━"))
-			fmt.Printf("  %s\n", cSubtle("Type 'exit' to return • 'clear' to reset screen"))
+			fmt.Printf("  %s\n", cSubtle("Type 'exit' to return • 'clear' to reset screen • '/image <path>' to attach • '/thinking' to show reasoning"))
 			fmt.Println()
 			continue
 		}
+		if input == "/thinking" {
+			showThinking()
+			continue
+		}
+		if strings.HasPrefix(input, "/image") {
+			path, question, _ := strings.Cut(strings.TrimSpace(strings.TrimPrefix(input, "/image")), " ")
+			if path == "" {
+				color.Yellow("  Usage: /image <path> [question]")
+				continue
+			}
+			img, err := ai.LoadImage(path)
+			if err != nil {
+				color.Red("  Error: %v", err)
+				continue
+			}
+			attached = append(attached, img)
+			fmt.Printf("  %s\n", cSubtle("📎 "+img.Name+" attached"))
+			if input = strings.TrimSpace(question); input == "" {
+				continue
+			}
+		}
 
-		spinner := ui.NewSpinner("Thinking")
-		spinner.Start()
-		resp, err := currentProvider.Send(input)
-		spinner.Stop()
+		currentProvider.Attach(attached...)
 
-		if err != nil {
-			color.Red("  Error: %v\n", err)
-		} else {
+		ctx, stop := commandContext("chat")
+		_, err := streamReply(ctx, currentProvider, input, "Thinking", func() {
 			fmt.Printf("\n  %s\n\n", cAI("Forge AI >"))
-
-			rendered := mdRenderer.Render(resp)
-			fmt.Println(rendered)
-
+		})
+		stop()
+
+		if isCancelled(err) {
+			color.Yellow("\n  Request cancelled")
+		} else if err != nil {
+			color.Red("  Error: %v", err)
+			printGuidance(err)
+			fmt.Println()
+		} else {
 			fmt.Println()
 		}
+
+		// Images stay attached after a failed request, a rate limit or a
+		// timeout among them, unless the model takes no images at all.
+		var noImages *ai.ImagesUnsupportedError
+		if err == nil || errors.As(err, &noImages) {
+			attached = nil
+		} else if len(attached) > 0 {
+			fmt.Printf("  %s\n", cSubtle(fmt.Sprintf("📎 %d image(s) still attached; send again to retry", len(attached))))
+		}
 	}
 }
 
@@ -490,27 +640,37 @@ func getAndValidateAPIKey(scanner *bufio.Scanner, keyName, keyURL, providerType,
 			return false
 		}
 
-		_, err = testProvider.Send("Hi")
+		ctx, stop := commandContext("validate")
+		_, err = testProvider.Send(ctx, "Hi")
+		stop()
 		spinner.Stop()
 
-		if err != nil {
-			errMsg := strings.ToLower(err.Error())
-			if strings.Contains(errMsg, "api key") || strings.Contains(errMsg, "unauthorized") ||
-				strings.Contains(errMsg, "authentication") || strings.Contains(errMsg, "invalid") ||
-				strings.Contains(errMsg, "401") || strings.Contains(errMsg, "403") {
-				color.Red("  ✗ API Key validation failed: %v", err)
-
-				if attempt < maxRetries {
-					fmt.Print("\n  Try again? [Y/n]: ")
-					scanner.Scan()
-					response := strings.ToLower(strings.TrimSpace(scanner.Text()))
-					if response == "n" || response == "no" {
-						return false
-					}
-					continue
+		if isCancelled(err) {
+			color.Yellow("  Validation cancelled")
+			return false
+		}
+
+		var authErr *ai.AuthError
+		if errors.As(err, &authErr) {
+			color.Red("  ✗ API Key validation failed: %v", err)
+			printGuidance(err)
+
+			if attempt < maxRetries {
+				fmt.Print("\n  Try again? [Y/n]: ")
+				scanner.Scan()
+				response := strings.ToLower(strings.TrimSpace(scanner.Text()))
+				if response == "n" || response == "no" {
+					return false
 				}
-				return false
+				continue
 			}
+			return false
+		}
+		if err != nil {
+			// The key was accepted; the test request failed for another
+			// reason (quota, rate limit, outage) that is worth knowing now.
+			color.Yellow("  ! Test request failed: %v", err)
+			printGuidance(err)
 		}
 
 		color.Green("  ✓ API Key validated successfully!")
@@ -566,13 +726,15 @@ func handleChangeAPIKey(scanner *bufio.Scanner) {
 
 func handleSwitchModel(scanner *bufio.Scanner) {
 	ui.PrintHeader("SWITCH AI PROVIDER")
-	fmt.Println("  1. Gemini 2.5 Flash")
-	fmt.Println("  2. Gemini Pro")
-	fmt.Println("  3. OpenAI GPT-3.5 Turbo")
-	fmt.Println("  4. OpenAI GPT-4")
-	fmt.Println("  5. Claude 3 Haiku")
-	fmt.Println("  6. Claude 3 Sonnet")
-	fmt.Println("  7. Ollama (Local)")
+	fmt.Println("  1. Google Gemini")
+	fmt.Println("  2. OpenAI")
+	fmt.Println("  3. Anthropic Claude")
+	fmt.Println("  4. Ollama (Local)")
+	fmt.Println("  5. OpenAI-compatible server")
+	plugins := ai.ListPlugins()
+	for i, name := range plugins {
+		fmt.Printf("  %d. %s (plugin)\n", 6+i, name)
+	}
 	fmt.Print("\n  Selection: ")
 	scanner.Scan()
 	choice := strings.TrimSpace(scanner.Text())
@@ -583,76 +745,61 @@ func handleSwitchModel(scanner *bufio.Scanner) {
 	var providerType string
 
 	switch choice {
-	case "1":
-		providerType = "gemini"
-		selectedModel = "gemini-2.5-flash"
-		if os.Getenv("GEMINI_API_KEY") == "" {
-			if !getAndValidateAPIKey(scanner, "GEMINI_API_KEY", "https://aistudio.google.com/app/apikey", providerType, selectedModel) {
-				return
-			}
+	case "1", "2", "3":
+		var keyName, keyURL, title string
+		switch choice {
+		case "1":
+			providerType, keyName, keyURL, title = "gemini", "GEMINI_API_KEY", "https://aistudio.google.com/app/apikey", "SELECT GEMINI MODEL"
+		case "2":
+			providerType, keyName, keyURL, title = "openai", "OPENAI_API_KEY", "https://platform.openai.com/api-keys", "SELECT OPENAI MODEL"
+		case "3":
+			providerType, keyName, keyURL, title = "claude", "ANTHROPIC_API_KEY", "https://console.anthropic.com/settings/keys", "SELECT CLAUDE MODEL"
 		}
-		p, err = ai.CreateProvider("gemini", selectedModel)
-	case "2":
-		providerType = "gemini"
-		selectedModel = "gemini-pro"
-		if os.Getenv("GEMINI_API_KEY") == "" {
-			if !getAndValidateAPIKey(scanner, "GEMINI_API_KEY", "https://aistudio.google.com/app/apikey", providerType, selectedModel) {
+		if os.Getenv(keyName) == "" {
+			if !getAndValidateAPIKey(scanner, keyName, keyURL, providerType, ai.FallbackModels[providerType][0]) {
 				return
 			}
 		}
-		p, err = ai.CreateProvider("gemini", selectedModel)
-	case "3":
-		providerType = "openai"
-		selectedModel = "gpt-3.5-turbo"
-		if os.Getenv("OPENAI_API_KEY") == "" {
-			if !getAndValidateAPIKey(scanner, "OPENAI_API_KEY", "https://platform.openai.com/api-keys", providerType, selectedModel) {
-				return
-			}
+		model, ok := chooseCloudModel(scanner, providerType, title)
+		if !ok {
+			return
 		}
-		p, err = ai.CreateProvider("openai", selectedModel)
+		selectedModel = model
+		p, err = ai.CreateProvider(providerType, selectedModel)
 	case "4":
-		providerType = "openai"
-		selectedModel = "gpt-4"
-		if os.Getenv("OPENAI_API_KEY") == "" {
-			if !getAndValidateAPIKey(scanner, "OPENAI_API_KEY", "https://platform.openai.com/api-keys", providerType, selectedModel) {
-				return
-			}
-		}
-		p, err = ai.CreateProvider("openai", selectedModel)
-	case "5":
-		providerType = "claude"
-		selectedModel = "claude-3-haiku-20240307"
-		if os.Getenv("ANTHROPIC_API_KEY") == "" {
-			if !getAndValidateAPIKey(scanner, "ANTHROPIC_API_KEY", "https://console.anthropic.com/settings/keys", providerType, selectedModel) {
-				return
-			}
-		}
-		p, err = ai.CreateProvider("claude", selectedModel)
-	case "6":
-		providerType = "claude"
-		selectedModel = "claude-3-sonnet-20240229"
-		if os.Getenv("ANTHROPIC_API_KEY") == "" {
-			if !getAndValidateAPIKey(scanner, "ANTHROPIC_API_KEY", "https://console.anthropic.com/settings/keys", providerType, selectedModel) {
-				return
-			}
-		}
-		p, err = ai.CreateProvider("claude", selectedModel)
-	case "7":
 		providerType = "ollama"
-		ui.PrintHeader("ENTER OLLAMA MODEL")
-		fmt.Print("  Model name: ")
-		scanner.Scan()
-		selectedModel = strings.TrimSpace(scanner.Text())
-		if selectedModel == "" {
-			selectedModel = "llama3"
+		model, ok := chooseOllamaModel(scanner)
+		if !ok {
+			time.Sleep(2 * time.Second)
+			return
 		}
+		selectedModel = model
 		p, err = ai.CreateProvider("ollama", selectedModel)
+	case "5":
+		providerType = "openai-compatible"
+		model, ok := configureOpenAICompatible(scanner)
+		if !ok {
+			time.Sleep(2 * time.Second)
+			return
+		}
+		selectedModel = model
+		p, err = ai.CreateProvider(providerType, selectedModel)
 	default:
-		return
+		n, convErr := strconv.Atoi(choice)
+		if convErr != nil || n < 6 || n-6 >= len(plugins) {
+			return
+		}
+		providerType = plugins[n-6]
+		model, ok := chooseCloudModel(scanner, providerType, "SELECT "+strings.ToUpper(providerType)+" MODEL")
+		if !ok {
+			return
+		}
+		selectedModel = model
+		p, err = ai.CreateProvider(providerType, selectedModel)
 	}
 
 	if err == nil {
-		currentProvider = p
+		currentProvider = ai.WithHistoryBudget(p)
 
 		if err := config.SaveLastModel(providerType, selectedModel); err != nil {
 			color.Red("  Warning: Could not save model preference: %v\n", err)
@@ -668,16 +815,19 @@ func handleSwitchModel(scanner *bufio.Scanner) {
 }
 
 func runOneShot(prompt string) error {
-	p, err := ai.NewProvider()
-	if err != nil {
-		return err
-	}
-	res, err := p.Send(prompt)
+	p, err := newProvider()
 	if err != nil {
 		return err
 	}
-	fmt.Println(res)
-	return nil
+	p.SetOptions(generationOptions("ask"))
+	ctx, stop := commandContext("ask")
+	defer stop()
+
+	_, err = p.Stream(ctx, prompt, func(token string) {
+		fmt.Print(token)
+	})
+	fmt.Println()
+	return err
 }
 
 func initConfig() {
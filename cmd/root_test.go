@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/broman0x/forgeai-cli/internal/ai"
+	"github.com/broman0x/forgeai-cli/internal/ai/aitest"
+)
+
+// A 1x1 PNG header is enough for LoadImage to accept the file.
+const tinyPNG = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00\x1f\x15\xc4\x89"
+
+func TestChatKeepsImagesAfterAFailedSend(t *testing.T) {
+	dir := setupTest(t)
+	path := filepath.Join(dir, "shot.png")
+	writeFile(t, path, tinyPNG)
+
+	fake := &aitest.FakeProvider{Model: "vision", Replies: []aitest.Reply{
+		{Err: &ai.RateLimitError{APIError: &ai.APIError{Provider: "openai", StatusCode: 429, Message: "slow down"}}},
+		{Text: "A button is cut off."},
+		{Text: "No image this time."},
+	}}
+	prev := currentProvider
+	currentProvider = fake
+	t.Cleanup(func() { currentProvider = prev })
+
+	captureOutput(t, func() {
+		startChatMode(answers("/image "+path+" what is wrong?", "what is wrong?", "thanks", "exit"))
+	})
+
+	calls := fake.Calls()
+	if len(calls) != 3 {
+		t.Fatalf("calls = %d, want 3", len(calls))
+	}
+	if len(calls[0].Images) != 1 || len(calls[1].Images) != 1 || calls[1].Images[0].Name != "shot.png" {
+		t.Fatalf("the image was not sent again after the rate limit: %+v", calls[1].Images)
+	}
+	if len(calls[2].Images) != 0 {
+		t.Fatalf("the image was sent again after a successful reply")
+	}
+}
+
+func TestChatDropsImagesTheModelCannotTake(t *testing.T) {
+	dir := setupTest(t)
+	path := filepath.Join(dir, "shot.png")
+	writeFile(t, path, tinyPNG)
+
+	fake := &aitest.FakeProvider{Model: "text", Replies: []aitest.Reply{
+		{Err: &ai.ImagesUnsupportedError{Provider: "ollama", Model: "text"}},
+		{Text: "ok"},
+	}}
+	prev := currentProvider
+	currentProvider = fake
+	t.Cleanup(func() { currentProvider = prev })
+
+	captureOutput(t, func() {
+		startChatMode(answers("/image "+path+" describe", "describe", "exit"))
+	})
+
+	if calls := fake.Calls(); len(calls) != 2 || len(calls[1].Images) != 0 {
+		t.Fatalf("calls = %+v", calls)
+	}
+}
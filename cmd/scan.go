@@ -22,7 +22,11 @@ var scanCmd = &cobra.Command{
 			cmd.Help()
 			return
 		}
-		prov, _ := ai.NewProvider()
+		prov, err := newProvider()
+		if err != nil {
+			color.Red("Error: %v", err)
+			return
+		}
 		runScanLogic(prov, args[0])
 	},
 }
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/broman0x/forgeai-cli/internal/ai"
+	"github.com/broman0x/forgeai-cli/internal/config"
+	"github.com/broman0x/forgeai-cli/internal/lang"
+	"github.com/broman0x/forgeai-cli/internal/ui"
+	"github.com/fatih/color"
+)
+
+// lastThinking is the reasoning behind the last streamed reply, for chat's
+// /thinking.
+var lastThinking string
+
+func thinkingDisplay() string {
+	switch mode := config.Load().ThinkingDisplay; mode {
+	case "full", "hidden":
+		return mode
+	}
+	return "collapsed"
+}
+
+func streamReply(ctx context.Context, prov ai.Provider, prompt, thinking string, onStart func()) (string, error) {
+	md := ui.NewMarkdownRenderer().NewStream(os.Stdout)
+	dim := color.New(color.FgHiBlack)
+	mode := thinkingDisplay()
+
+	spinner := ui.NewSpinner(thinking)
+	spinner.Start()
+	ctx = showRateLimitWait(ctx, spinner, thinking)
+
+	var reasoning strings.Builder
+	var began time.Time
+	started := false
+	lastThinking = ""
+
+	// Thinking comes before the answer: "full" streams it dimmed in place of
+	// the spinner, "collapsed" counts words on the spinner and leaves a
+	// summary line once the answer starts. Thinking between later tool
+	// rounds is only kept for /thinking.
+	ctx = ai.WithThinking(ctx, func(text string) {
+		if started {
+			reasoning.WriteString(text)
+			return
+		}
+		if reasoning.Len() == 0 {
+			began = time.Now()
+			if mode == "full" {
+				spinner.Stop()
+				dim.Printf("\n  ▾ %s\n  ", lang.T("thinking_label"))
+			}
+		}
+		reasoning.WriteString(text)
+		switch mode {
+		case "full":
+			dim.Print(strings.ReplaceAll(text, "\n", "\n  "))
+		case "collapsed":
+			words := fmt.Sprintf(lang.T("thinking_words"), len(strings.Fields(reasoning.String())))
+			spinner.SetMessage(fmt.Sprintf("%s (%s)", thinking, words))
+		}
+	})
+
+	resp, err := prov.Stream(ctx, prompt, func(token string) {
+		if !started {
+			started = true
+			spinner.Stop()
+			if reasoning.Len() > 0 {
+				switch mode {
+				case "full":
+					fmt.Println()
+				case "collapsed":
+					secs := int(time.Since(began).Round(time.Second) / time.Second)
+					words := len(strings.Fields(reasoning.String()))
+					dim.Printf("\n  ▸ %s\n", fmt.Sprintf(lang.T("thinking_summary"), secs, words))
+				}
+			}
+			if onStart != nil {
+				onStart()
+			}
+		}
+		md.Feed(token)
+	})
+
+	if !started {
+		spinner.Stop()
+	}
+	md.Flush()
+	lastThinking = strings.TrimSpace(reasoning.String())
+
+	return resp, err
+}
+
+// showThinking prints the reasoning behind the last reply, dimmed.
+func showThinking() {
+	if lastThinking == "" {
+		color.Yellow("  %s", lang.T("thinking_none"))
+		return
+	}
+	dim := color.New(color.FgHiBlack)
+	dim.Printf("\n  ▾ %s\n  %s\n", lang.T("thinking_label"), strings.ReplaceAll(lastThinking, "\n", "\n  "))
+}
+
+// showRateLimitWait makes a call held back by the client-side rate limiter
+// count down on spinner instead of looking stuck.
+func showRateLimitWait(ctx context.Context, spinner *ui.Spinner, message string) context.Context {
+	return ai.WithWaitNotifier(ctx, func(remaining time.Duration) {
+		if remaining <= 0 {
+			spinner.SetMessage(message)
+			return
+		}
+		secs := int((remaining + time.Second - 1) / time.Second)
+		spinner.SetMessage(fmt.Sprintf("%s (%s)", message, fmt.Sprintf(lang.T("rate_limit_wait"), secs)))
+	})
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/broman0x/forgeai-cli/internal/ai"
+	"github.com/broman0x/forgeai-cli/internal/workspace"
+	"github.com/fatih/color"
+)
+
+const maxToolArgsShown = 80
+
+// chatTools returns the read-only workspace tools for the current directory,
+// each announcing itself on a dim line as the model calls it.
+func chatTools() []ai.Tool {
+	ws, err := workspace.New(".")
+	if err != nil {
+		return nil
+	}
+
+	tools := ws.Tools()
+	for i := range tools {
+		name, run := tools[i].Name, tools[i].Run
+		tools[i].Run = func(ctx context.Context, args json.RawMessage) (string, error) {
+			fmt.Print("\r\033[K")
+			fmt.Println(color.New(color.FgHiBlack).Sprintf("  ⚙ %s %s", name, toolArgs(args)))
+			return run(ctx, args)
+		}
+	}
+	return tools
+}
+
+func toolArgs(args json.RawMessage) string {
+	var compact map[string]interface{}
+	if json.Unmarshal(args, &compact) != nil || len(compact) == 0 {
+		return ""
+	}
+	out, _ := json.Marshal(compact)
+	s := string(out)
+	if len(s) > maxToolArgsShown {
+		s = s[:maxToolArgsShown] + "…"
+	}
+	return s
+}
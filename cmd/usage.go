@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/broman0x/forgeai-cli/internal/usage"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	usageDays    int
+	usageSession bool
+)
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Show token usage and estimated cost",
+	Run: func(cmd *cobra.Command, args []string) {
+		runUsageReport()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(usageCmd)
+	usageCmd.Flags().IntVarP(&usageDays, "days", "d", 1, "Number of days to report (1 = today)")
+	usageCmd.Flags().BoolVarP(&usageSession, "session", "s", false, "Report only the most recent session")
+}
+
+func runUsageReport() {
+	ledger, err := usage.Load()
+	if err != nil {
+		color.Red("  Error: %v", err)
+		return
+	}
+
+	cTitle := color.New(color.FgHiCyan, color.Bold).SprintFunc()
+	cSubtle := color.New(color.FgHiBlack).SprintFunc()
+
+	var entries []usage.Entry
+	var title string
+
+	if usageSession {
+		var session string
+		session, entries = ledger.LastSession()
+		title = "USAGE - SESSION " + session
+	} else {
+		if usageDays < 1 {
+			usageDays = 1
+		}
+		since := time.Now().AddDate(0, 0, -(usageDays - 1)).Format("2006-01-02")
+		entries = ledger.Since(since)
+		if usageDays == 1 {
+			title = "USAGE - TODAY"
+		} else {
+			title = fmt.Sprintf("USAGE - LAST %d DAYS", usageDays)
+		}
+	}
+
+	fmt.Println()
+	fmt.Println(cTitle("  " + title))
+	fmt.Println(cSubtle("  ───────────────────────────────────────────"))
+
+	if len(entries) == 0 {
+		color.Yellow("  No usage recorded yet.")
+		fmt.Println()
+		return
+	}
+
+	rows := usage.Summarize(entries, func(e usage.Entry) string {
+		return e.Provider + "\x00" + e.Model + "\x00" + e.Command
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  PROVIDER\tMODEL\tCOMMAND\tREQUESTS\tINPUT\tOUTPUT\tCOST")
+
+	var total usage.Entry
+	for _, r := range rows {
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%d\t%d\t%d\t%s\n", r.Provider, r.Model, r.Command, r.Requests, r.InputTokens, r.OutputTokens, formatCost(r.Cost))
+		total.Requests += r.Requests
+		total.InputTokens += r.InputTokens
+		total.OutputTokens += r.OutputTokens
+		total.Cost += r.Cost
+	}
+	fmt.Fprintf(w, "  %s\t\t\t%d\t%d\t%d\t%s\n", "TOTAL", total.Requests, total.InputTokens, total.OutputTokens, formatCost(total.Cost))
+	w.Flush()
+
+	if !usageSession && usageDays > 1 {
+		days := usage.Summarize(entries, func(e usage.Entry) string { return e.Day })
+
+		fmt.Println()
+		fmt.Println(cTitle("  BY DAY"))
+		fmt.Println(cSubtle("  ───────────────────────────────────────────"))
+
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, d := range days {
+			fmt.Fprintf(w, "  %s\t%d req\t%d in\t%d out\t%s\n", d.Day, d.Requests, d.InputTokens, d.OutputTokens, formatCost(d.Cost))
+		}
+		w.Flush()
+	}
+
+	fmt.Println()
+	fmt.Println(cSubtle("  Costs are estimates based on public list prices."))
+	fmt.Println()
+}
+
+func formatCost(cost float64) string {
+	return fmt.Sprintf("$%.4f", cost)
+}
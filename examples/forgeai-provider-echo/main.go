@@ -0,0 +1,108 @@
+// Command forgeai-provider-echo is a minimal ForgeAI provider plugin. It
+// answers every prompt by repeating it, which is enough to see the protocol
+// at work:
+//
+//	go build -o ~/.config/forgeai/plugins/forgeai-provider-echo ./examples/forgeai-provider-echo
+//	forge compare --models echo:echo,echo:shout "hello there"
+//
+// ForgeAI starts the plugin once per request, writes one JSON request line to
+// stdin and reads JSON lines from stdout until the plugin exits. A real plugin
+// would forward the request to its backend here instead.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"strings"
+)
+
+type message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type request struct {
+	Version  int       `json:"version"`
+	Method   string    `json:"method"`
+	Model    string    `json:"model"`
+	System   string    `json:"system"`
+	Messages []message `json:"messages"`
+}
+
+type usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type replyError struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+type reply struct {
+	Type   string      `json:"type"`
+	Text   string      `json:"text,omitempty"`
+	Models []string    `json:"models,omitempty"`
+	Usage  *usage      `json:"usage,omitempty"`
+	Error  *replyError `json:"error,omitempty"`
+}
+
+var out = json.NewEncoder(os.Stdout)
+
+func main() {
+	in := bufio.NewReader(os.Stdin)
+	line, err := in.ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		fail("unavailable", "no request on stdin")
+	}
+
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		fail("", "invalid request: "+err.Error())
+	}
+	if req.Version != 1 {
+		fail("", "unsupported protocol version")
+	}
+
+	switch req.Method {
+	case "models":
+		out.Encode(reply{Type: "models", Models: []string{"echo", "shout"}})
+	case "send", "stream":
+		answer(req)
+	default:
+		fail("", "unknown method "+req.Method)
+	}
+}
+
+func answer(req request) {
+	if len(req.Messages) == 0 {
+		fail("", "no messages")
+	}
+	prompt := req.Messages[len(req.Messages)-1].Content
+	if strings.TrimSpace(prompt) == "" {
+		fail("content_blocked", "refusing to echo an empty prompt")
+	}
+
+	text := prompt
+	if req.Model == "shout" {
+		text = strings.ToUpper(prompt)
+	}
+
+	// Streaming is optional: a plugin may send only the final "done" line,
+	// even for "stream" requests.
+	if req.Method == "stream" {
+		words := strings.SplitAfter(text, " ")
+		for _, w := range words {
+			out.Encode(reply{Type: "token", Text: w})
+		}
+	}
+
+	tokens := len(strings.Fields(prompt))
+	out.Encode(reply{Type: "done", Text: text, Usage: &usage{InputTokens: tokens, OutputTokens: tokens}})
+}
+
+func fail(kind, msg string) {
+	out.Encode(reply{Type: "error", Error: &replyError{Kind: kind, Message: msg}})
+	os.Exit(1)
+}
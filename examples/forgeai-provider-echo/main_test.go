@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	if os.Getenv("RUN_ECHO_PLUGIN") != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func run(t *testing.T, req string) []reply {
+	t.Helper()
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), "RUN_ECHO_PLUGIN=1")
+	cmd.Stdin = strings.NewReader(req + "\n")
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Run()
+
+	var replies []reply
+	dec := json.NewDecoder(&stdout)
+	for dec.More() {
+		var r reply
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		replies = append(replies, r)
+	}
+	return replies
+}
+
+func TestModels(t *testing.T) {
+	replies := run(t, `{"version":1,"method":"models"}`)
+	if len(replies) != 1 || replies[0].Type != "models" || strings.Join(replies[0].Models, ",") != "echo,shout" {
+		t.Fatalf("got %+v", replies)
+	}
+}
+
+func TestStreamShout(t *testing.T) {
+	replies := run(t, `{"version":1,"method":"stream","model":"shout","messages":[{"role":"user","content":"hello there"}]}`)
+	if len(replies) != 3 || replies[0].Text != "HELLO " || replies[1].Text != "THERE" {
+		t.Fatalf("got %+v", replies)
+	}
+	done := replies[2]
+	if done.Type != "done" || done.Text != "HELLO THERE" || done.Usage == nil || done.Usage.InputTokens != 2 {
+		t.Fatalf("got %+v", done)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	replies := run(t, `{"version":1,"method":"send","model":"echo","messages":[{"role":"user","content":"  "}]}`)
+	if len(replies) != 1 || replies[0].Type != "error" || replies[0].Error.Kind != "content_blocked" {
+		t.Fatalf("got %+v", replies)
+	}
+	replies = run(t, `{"version":2,"method":"send"}`)
+	if len(replies) != 1 || replies[0].Type != "error" {
+		t.Fatalf("got %+v", replies)
+	}
+}
@@ -0,0 +1,215 @@
+// Package aitest provides a scriptable fake Provider and cassette helpers so
+// code built on internal/ai can be tested without network access.
+package aitest
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/broman0x/forgeai-cli/internal/ai"
+	"github.com/broman0x/forgeai-cli/internal/cassette"
+	"github.com/broman0x/forgeai-cli/internal/config"
+)
+
+type Reply struct {
+	Text string
+	Err  error
+}
+
+type Call struct {
+	System  string
+	Prompt  string
+	History []ai.Message
+	Images  []ai.Image
+	Options ai.GenerationOptions
+	Format  *ai.Schema
+	Stream  bool
+}
+
+type FakeProvider struct {
+	Model   string
+	Replies []Reply
+	Respond func(prompt string) (string, error)
+
+	mu      sync.Mutex
+	calls   []Call
+	system  string
+	tools   []ai.Tool
+	pending []ai.Image
+	options ai.GenerationOptions
+	format  *ai.Schema
+	history []ai.Message
+}
+
+func NewFakeProvider(replies ...string) *FakeProvider {
+	f := &FakeProvider{Model: "fake"}
+	for _, r := range replies {
+		f.Replies = append(f.Replies, Reply{Text: r})
+	}
+	return f
+}
+
+func (f *FakeProvider) Name() string { return "Fake (" + f.Model + ")" }
+
+func (f *FakeProvider) ModelName() string { return f.Model }
+
+func (f *FakeProvider) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.history = nil
+}
+
+func (f *FakeProvider) SetSystemPrompt(prompt string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.system = prompt
+}
+
+// SetTools only records the tools; the fake never calls them.
+func (f *FakeProvider) SetTools(tools []ai.Tool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tools = tools
+}
+
+func (f *FakeProvider) SetOptions(opts ai.GenerationOptions) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.options = opts
+}
+
+func (f *FakeProvider) SetResponseFormat(schema *ai.Schema) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.format = schema
+}
+
+func (f *FakeProvider) Attach(images ...ai.Image) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pending = append(f.pending, images...)
+}
+
+func (f *FakeProvider) Tools() []ai.Tool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]ai.Tool(nil), f.tools...)
+}
+
+func (f *FakeProvider) Conversation() []ai.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]ai.Message(nil), f.history...)
+}
+
+func (f *FakeProvider) SetConversation(messages []ai.Message) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.history = append([]ai.Message(nil), messages...)
+}
+
+func (f *FakeProvider) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+func (f *FakeProvider) next(prompt string, stream bool) (string, error) {
+	f.mu.Lock()
+	images := f.pending
+	f.pending = nil
+	f.calls = append(f.calls, Call{
+		System:  f.system,
+		Prompt:  prompt,
+		History: append([]ai.Message(nil), f.history...),
+		Images:  images,
+		Options: f.options,
+		Format:  f.format,
+		Stream:  stream,
+	})
+
+	var reply Reply
+	switch {
+	case len(f.Replies) > 0:
+		reply = f.Replies[0]
+		f.Replies = f.Replies[1:]
+	case f.Respond != nil:
+		respond := f.Respond
+		f.mu.Unlock()
+		text, err := respond(prompt)
+		f.mu.Lock()
+		reply = Reply{Text: text, Err: err}
+	default:
+		reply = Reply{Err: &ai.APIError{Provider: "fake", Message: "no scripted reply left"}}
+	}
+
+	if reply.Err == nil {
+		f.history = append(f.history,
+			ai.Message{Role: "user", Content: prompt, Images: images},
+			ai.Message{Role: "assistant", Content: reply.Text})
+	}
+	f.mu.Unlock()
+	return reply.Text, reply.Err
+}
+
+func (f *FakeProvider) Send(ctx context.Context, prompt string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return f.next(prompt, false)
+}
+
+// Stream emits the scripted reply word by word, keeping the whitespace, so
+// renderers see realistic token boundaries.
+func (f *FakeProvider) Stream(ctx context.Context, prompt string, onToken func(string)) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	text, err := f.next(prompt, true)
+	if err != nil {
+		return "", err
+	}
+	for _, tok := range strings.SplitAfter(text, " ") {
+		if tok != "" {
+			onToken(tok)
+		}
+	}
+	return text, nil
+}
+
+// UseCassette routes every provider created during the test through the
+// cassette at path. Set FORGEAI_RECORD=1 to re-record it against the real
+// APIs instead of replaying.
+func UseCassette(t testing.TB, path string) *cassette.Recorder {
+	t.Helper()
+
+	mode := cassette.Replay
+	if os.Getenv("FORGEAI_RECORD") != "" {
+		mode = cassette.Record
+	}
+	rec, err := cassette.New(path, mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prev := ai.Transport
+	ai.Transport = rec
+	t.Cleanup(func() { ai.Transport = prev })
+	return rec
+}
+
+// Isolate points the config dir at a temp directory so usage, caches and
+// config written during a test never touch the real user's files.
+func Isolate(t testing.TB) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("APPDATA", "")
+	config.ResetCache()
+	t.Cleanup(config.ResetCache)
+	return dir
+}
@@ -2,6 +2,7 @@ package ai
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,39 +11,172 @@ import (
 	"time"
 )
 
+const claudeURL = "https://api.anthropic.com/v1/messages"
+
 type ClaudeProvider struct {
 	ApiKey  string
 	Model   string
+	System  string
 	Client  *http.Client
+	Retry   RetryPolicy
 	History []claudeMessage
+
+	tools   []Tool
+	pending []Image
+	options GenerationOptions
+	format  *Schema
 }
 
+// claudeMessage holds plain text and images for history turns; Blocks is only
+// set for the tool_use and tool_result turns exchanged within a single prompt.
 type claudeMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string
+	Content string
+	Images  []Image
+	Blocks  []claudeBlock
+}
+
+func (m claudeMessage) MarshalJSON() ([]byte, error) {
+	if m.Blocks == nil && len(m.Images) > 0 {
+		// Claude reads images best when they come before the question.
+		for _, img := range m.Images {
+			m.Blocks = append(m.Blocks, claudeBlock{Type: "image", Source: &claudeImageSource{
+				Type:      "base64",
+				MediaType: img.MIMEType,
+				Data:      img.base64(),
+			}})
+		}
+		m.Blocks = append(m.Blocks, claudeBlock{Type: "text", Text: m.Content})
+	}
+	if m.Blocks == nil {
+		return json.Marshal(struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		}{m.Role, m.Content})
+	}
+	return json.Marshal(struct {
+		Role    string        `json:"role"`
+		Content []claudeBlock `json:"content"`
+	}{m.Role, m.Blocks})
+}
+
+type claudeBlock struct {
+	Type      string             `json:"type"`
+	Text      string             `json:"text,omitempty"`
+	ID        string             `json:"id,omitempty"`
+	Name      string             `json:"name,omitempty"`
+	Input     json.RawMessage    `json:"input,omitempty"`
+	ToolUseID string             `json:"tool_use_id,omitempty"`
+	Content   string             `json:"content,omitempty"`
+	Source    *claudeImageSource `json:"source,omitempty"`
+	Thinking  string             `json:"thinking,omitempty"`
+	Signature string             `json:"signature,omitempty"`
+	Data      string             `json:"data,omitempty"`
+}
+
+type claudeImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+type claudeTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+type claudeToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+type claudeThinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens"`
 }
 
 type claudeRequest struct {
-	Model     string          `json:"model"`
-	Messages  []claudeMessage `json:"messages"`
-	MaxTokens int             `json:"max_tokens"`
+	Model         string            `json:"model"`
+	System        string            `json:"system,omitempty"`
+	Messages      []claudeMessage   `json:"messages"`
+	Tools         []claudeTool      `json:"tools,omitempty"`
+	ToolChoice    *claudeToolChoice `json:"tool_choice,omitempty"`
+	MaxTokens     int               `json:"max_tokens"`
+	Temperature   *float64          `json:"temperature,omitempty"`
+	TopP          *float64          `json:"top_p,omitempty"`
+	StopSequences []string          `json:"stop_sequences,omitempty"`
+	Thinking      *claudeThinking   `json:"thinking,omitempty"`
+	Stream        bool              `json:"stream,omitempty"`
+}
+
+const defaultClaudeMaxTokens = 4096
+
+// The API rejects max_tokens above the model's output limit and requires the
+// field, so models without a known limit get the conservative default.
+func claudeMaxTokens(model string) int {
+	if limit := LookupModel(model).MaxOutput; limit > 0 {
+		return limit
+	}
+	return defaultClaudeMaxTokens
+}
+
+type claudeError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+type claudeUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
 }
 
 type claudeResponse struct {
-	Content []struct {
-		Text string `json:"text"`
-	} `json:"content"`
-	Error *struct {
-		Type    string `json:"type"`
-		Message string `json:"message"`
-	} `json:"error,omitempty"`
+	Content    []claudeBlock `json:"content"`
+	StopReason string        `json:"stop_reason"`
+	Usage      claudeUsage   `json:"usage"`
+	Error      *claudeError  `json:"error,omitempty"`
+}
+
+type claudeStreamEvent struct {
+	Type    string `json:"type"`
+	Index   int    `json:"index"`
+	Message struct {
+		Usage claudeUsage `json:"usage"`
+	} `json:"message"`
+	ContentBlock claudeBlock `json:"content_block"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		Thinking    string `json:"thinking"`
+		Signature   string `json:"signature"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage claudeUsage  `json:"usage"`
+	Error *claudeError `json:"error,omitempty"`
+}
+
+// Errors sent mid-stream arrive after a 200, so map their type back to the
+// status the API would have used.
+func claudeErrorStatus(errType string) int {
+	switch errType {
+	case "overloaded_error":
+		return 529
+	case "rate_limit_error":
+		return http.StatusTooManyRequests
+	case "api_error":
+		return http.StatusInternalServerError
+	}
+	return 0
 }
 
 func newClaudeProvider(apiKey, model string) *ClaudeProvider {
 	return &ClaudeProvider{
 		ApiKey:  apiKey,
 		Model:   model,
-		Client:  &http.Client{Timeout: 120 * time.Second},
+		Client:  newHTTPClient("claude", 120*time.Second),
+		Retry:   retryPolicyFromConfig("claude", apiKey),
 		History: []claudeMessage{},
 	}
 }
@@ -51,49 +185,357 @@ func (c *ClaudeProvider) Name() string {
 	return "Claude (" + c.Model + ")"
 }
 
+func (c *ClaudeProvider) ModelName() string { return c.Model }
+
 func (c *ClaudeProvider) Reset() {
 	c.History = []claudeMessage{}
 }
 
-func (c *ClaudeProvider) Send(prompt string) (string, error) {
-	url := "https://api.anthropic.com/v1/messages"
+func (c *ClaudeProvider) SetSystemPrompt(prompt string) {
+	c.System = prompt
+}
 
-	userMsg := claudeMessage{Role: "user", Content: prompt}
-	currentContext := append(c.History, userMsg)
+func (c *ClaudeProvider) SetTools(tools []Tool) {
+	c.tools = tools
+}
 
-	payload, _ := json.Marshal(claudeRequest{
-		Model:     c.Model,
-		Messages:  currentContext,
-		MaxTokens: 4096,
-	})
+func (c *ClaudeProvider) SetOptions(opts GenerationOptions) {
+	c.options = opts
+}
+
+// Claude has no JSON mode, so structured output is a tool whose input schema
+// is the response schema and which the model is forced to call.
+func (c *ClaudeProvider) SetResponseFormat(schema *Schema) {
+	c.format = schema
+}
+
+func (c *ClaudeProvider) requestTools() ([]claudeTool, *claudeToolChoice) {
+	tools := claudeTools(c.tools)
+	if c.format == nil {
+		return tools, nil
+	}
+	desc := c.format.Description
+	if desc == "" {
+		desc = "Return the response in this structure."
+	}
+	tools = append(tools, claudeTool{Name: c.format.Name, Description: desc, InputSchema: c.format.Schema})
+	return tools, &claudeToolChoice{Type: "tool", Name: c.format.Name}
+}
+
+// turn is claudeTurn plus structured output: a call to the response tool is
+// the answer, with its input as the JSON text.
+func (c *ClaudeProvider) turn(blocks []claudeBlock) (string, []ToolCall, []claudeBlock) {
+	text, calls, echo := claudeTurn(blocks)
+	if c.format == nil {
+		return text, calls, echo
+	}
+	for _, call := range calls {
+		if call.Name == c.format.Name {
+			return string(call.Arguments), nil, echo
+		}
+	}
+	return text, calls, echo
+}
+
+func (c *ClaudeProvider) Attach(images ...Image) {
+	c.pending = append(c.pending, images...)
+}
+
+func (c *ClaudeProvider) takeImages() ([]Image, error) {
+	images := c.pending
+	c.pending = nil
+	return images, checkImages("claude", c.Model, images)
+}
+
+func claudeTools(tools []Tool) []claudeTool {
+	var out []claudeTool
+	for _, t := range tools {
+		out = append(out, claudeTool{Name: t.Name, Description: t.Description, InputSchema: t.Parameters})
+	}
+	return out
+}
+
+// claudeTurn splits a reply into its text and tool calls, and returns the
+// blocks to echo back as the assistant turn (empty text blocks are rejected,
+// and thinking blocks must come back unchanged while tools are in use).
+func claudeTurn(blocks []claudeBlock) (string, []ToolCall, []claudeBlock) {
+	var sb strings.Builder
+	var calls []ToolCall
+	var echo []claudeBlock
+	for _, b := range blocks {
+		switch b.Type {
+		case "text":
+			sb.WriteString(b.Text)
+			if b.Text != "" {
+				echo = append(echo, b)
+			}
+		case "tool_use":
+			if len(b.Input) == 0 {
+				b.Input = json.RawMessage("{}")
+			}
+			calls = append(calls, ToolCall{ID: b.ID, Name: b.Name, Arguments: b.Input})
+			echo = append(echo, b)
+		case "thinking", "redacted_thinking":
+			echo = append(echo, b)
+		}
+	}
+	return sb.String(), calls, echo
+}
+
+func claudeTokens(system string, messages []claudeMessage) int {
+	total := EstimateTokens(system)
+	for _, m := range messages {
+		total += EstimateTokens(m.Content) + len(m.Images)*imageTokens
+		for _, b := range m.Blocks {
+			total += estimateTexts([]string{b.Text, b.Content, b.Thinking, string(b.Input)})
+			if b.Source != nil {
+				total += imageTokens
+			}
+		}
+	}
+	return total
+}
+
+func appendClaudeToolResults(messages []claudeMessage, turn []claudeBlock, calls []ToolCall, results []string) []claudeMessage {
+	var out []claudeBlock
+	for i, call := range calls {
+		out = append(out, claudeBlock{Type: "tool_result", ToolUseID: call.ID, Content: results[i]})
+	}
+	return append(messages,
+		claudeMessage{Role: "assistant", Blocks: turn},
+		claudeMessage{Role: "user", Blocks: out})
+}
+
+func (c *ClaudeProvider) Conversation() []Message {
+	messages := make([]Message, len(c.History))
+	for i, m := range c.History {
+		messages[i] = Message{Role: m.Role, Content: m.Content, Images: m.Images}
+	}
+	return messages
+}
+
+func (c *ClaudeProvider) SetConversation(messages []Message) {
+	c.History = make([]claudeMessage, len(messages))
+	for i, m := range messages {
+		c.History[i] = claudeMessage{Role: m.Role, Content: m.Content, Images: m.Images}
+	}
+}
+
+// thinkingBudget is 0 for structured output: Claude cannot think while
+// forced to call the response tool.
+func (c *ClaudeProvider) thinkingBudget() int {
+	if c.format != nil || !LookupModel(c.Model).Reasoning {
+		return 0
+	}
+	return c.options.thinkingBudget()
+}
 
-	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(payload))
+func (c *ClaudeProvider) newRequest(ctx context.Context, messages []claudeMessage, stream bool) *http.Request {
+	tools, choice := c.requestTools()
+	maxTokens := claudeMaxTokens(c.Model)
+	if c.options.MaxTokens > 0 {
+		maxTokens = c.options.MaxTokens
+	}
+	body := claudeRequest{
+		Model:         c.Model,
+		System:        c.System,
+		Messages:      messages,
+		Tools:         tools,
+		ToolChoice:    choice,
+		MaxTokens:     maxTokens,
+		Temperature:   c.options.Temperature,
+		TopP:          c.options.TopP,
+		StopSequences: c.options.Stop,
+		Stream:        stream,
+	}
+	// Thinking counts against max_tokens and rules out sampling settings.
+	if budget := c.thinkingBudget(); budget > 0 {
+		body.Thinking = &claudeThinking{Type: "enabled", BudgetTokens: budget}
+		if body.MaxTokens <= budget {
+			body.MaxTokens += budget
+		}
+		body.Temperature = nil
+		body.TopP = nil
+	}
+	payload, _ := json.Marshal(body)
+
+	ctx = withTokens(ctx, claudeTokens(c.System, messages))
+	req, _ := http.NewRequestWithContext(ctx, "POST", claudeURL, bytes.NewBuffer(payload))
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("x-api-key", c.ApiKey)
 	req.Header.Set("anthropic-version", "2023-06-01")
+	return req
+}
 
-	resp, err := c.Client.Do(req)
+func (c *ClaudeProvider) Send(ctx context.Context, prompt string) (string, error) {
+	images, err := c.takeImages()
 	if err != nil {
 		return "", err
 	}
+	userMsg := claudeMessage{Role: "user", Content: prompt, Images: images}
+	currentContext := append(c.History, userMsg)
+	messages := append([]claudeMessage(nil), currentContext...)
+
+	var turn []claudeBlock
+	ans, err := toolLoop(ctx, c.tools, func() (string, []ToolCall, error) {
+		blocks, err := c.complete(ctx, messages)
+		text, calls, echo := c.turn(blocks)
+		turn = echo
+		return text, calls, err
+	}, func(calls []ToolCall, results []string) {
+		messages = appendClaudeToolResults(messages, turn, calls, results)
+	})
+	if err != nil {
+		return "", classifyError("claude", err)
+	}
+
+	ans = strings.TrimSpace(ans)
+	c.History = append(currentContext, claudeMessage{Role: "assistant", Content: ans})
+	return ans, nil
+}
+
+func (c *ClaudeProvider) complete(ctx context.Context, messages []claudeMessage) ([]claudeBlock, error) {
+	resp, err := c.Retry.Do(c.Client, c.newRequest(ctx, messages, false))
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
 
 	body, _ := io.ReadAll(resp.Body)
 
 	var res claudeResponse
 	if err := json.Unmarshal(body, &res); err != nil {
-		return "", fmt.Errorf("parse error: %s", string(body))
+		if resp.StatusCode != http.StatusOK {
+			return nil, newAPIError("claude", resp.StatusCode, body)
+		}
+		return nil, fmt.Errorf("parse error: %s", string(body))
 	}
 
 	if res.Error != nil {
-		return "", fmt.Errorf("claude error: %s", res.Error.Message)
+		return nil, &APIError{Provider: "claude", StatusCode: resp.StatusCode, Code: res.Error.Type, Message: res.Error.Message}
+	}
+
+	recordUsage(ctx, "claude", c.Model, Usage{InputTokens: res.Usage.InputTokens, OutputTokens: res.Usage.OutputTokens})
+
+	if res.StopReason == "refusal" {
+		return nil, contentFiltered("claude")
+	}
+	if len(res.Content) == 0 {
+		return nil, fmt.Errorf("empty response")
+	}
+	for _, b := range res.Content {
+		if b.Type == "thinking" {
+			emitThinking(ctx, b.Thinking)
+		}
+	}
+	return res.Content, nil
+}
+
+func (c *ClaudeProvider) Stream(ctx context.Context, prompt string, onToken func(string)) (string, error) {
+	images, err := c.takeImages()
+	if err != nil {
+		return "", err
+	}
+	userMsg := claudeMessage{Role: "user", Content: prompt, Images: images}
+	currentContext := append(c.History, userMsg)
+	messages := append([]claudeMessage(nil), currentContext...)
+
+	var turn []claudeBlock
+	ans, err := toolLoop(ctx, c.tools, func() (string, []ToolCall, error) {
+		blocks, err := c.streamOnce(ctx, messages, onToken)
+		text, calls, echo := c.turn(blocks)
+		turn = echo
+		if text != "" && len(calls) > 0 {
+			onToken("\n\n")
+		}
+		return text, calls, err
+	}, func(calls []ToolCall, results []string) {
+		messages = appendClaudeToolResults(messages, turn, calls, results)
+	})
+	if err != nil {
+		return "", classifyError("claude", err)
+	}
+
+	ans = strings.TrimSpace(ans)
+	if ans == "" {
+		return "", fmt.Errorf("empty response")
+	}
+	c.History = append(currentContext, claudeMessage{Role: "assistant", Content: ans})
+	return ans, nil
+}
+
+func (c *ClaudeProvider) streamOnce(ctx context.Context, messages []claudeMessage, onToken func(string)) ([]claudeBlock, error) {
+	resp, err := c.Retry.Do(c.Client, c.newRequest(ctx, messages, true))
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	if len(res.Content) > 0 {
-		ans := strings.TrimSpace(res.Content[0].Text)
-		c.History = append(currentContext, claudeMessage{Role: "assistant", Content: ans})
-		return ans, nil
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		var res claudeResponse
+		if json.Unmarshal(body, &res) == nil && res.Error != nil {
+			return nil, &APIError{Provider: "claude", StatusCode: resp.StatusCode, Code: res.Error.Type, Message: res.Error.Message}
+		}
+		return nil, newAPIError("claude", resp.StatusCode, body)
 	}
 
-	return "", fmt.Errorf("empty response")
+	var blocks []claudeBlock
+	var input []string
+	var tokens Usage
+	err = readSSE(resp.Body, func(_ string, data []byte) error {
+		var event claudeStreamEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return fmt.Errorf("parse error: %s", string(data))
+		}
+		for len(blocks) <= event.Index {
+			blocks = append(blocks, claudeBlock{Type: "text"})
+			input = append(input, "")
+		}
+		switch event.Type {
+		case "error":
+			if event.Error != nil {
+				return &APIError{Provider: "claude", StatusCode: claudeErrorStatus(event.Error.Type), Code: event.Error.Type, Message: event.Error.Message}
+			}
+			return newAPIError("claude", 0, data)
+		case "message_start":
+			tokens.InputTokens = event.Message.Usage.InputTokens
+			tokens.OutputTokens = event.Message.Usage.OutputTokens
+		case "message_delta":
+			tokens.OutputTokens = event.Usage.OutputTokens
+			if event.Delta.StopReason == "refusal" {
+				return contentFiltered("claude")
+			}
+		case "content_block_start":
+			blocks[event.Index] = event.ContentBlock
+			blocks[event.Index].Input = nil
+		case "content_block_delta":
+			switch event.Delta.Type {
+			case "text_delta":
+				if event.Delta.Text != "" {
+					blocks[event.Index].Text += event.Delta.Text
+					onToken(event.Delta.Text)
+				}
+			case "thinking_delta":
+				blocks[event.Index].Thinking += event.Delta.Thinking
+				emitThinking(ctx, event.Delta.Thinking)
+			case "signature_delta":
+				blocks[event.Index].Signature += event.Delta.Signature
+			case "input_json_delta":
+				input[event.Index] += event.Delta.PartialJSON
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	recordUsage(ctx, "claude", c.Model, tokens)
+
+	for i := range blocks {
+		if input[i] != "" {
+			blocks[i].Input = json.RawMessage(input[i])
+		}
+	}
+	return blocks, nil
 }
@@ -0,0 +1,268 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/broman0x/forgeai-cli/internal/config"
+)
+
+const (
+	defaultCacheTTL      = 7 * 24 * time.Hour
+	defaultCacheMaxBytes = 100 << 20
+)
+
+type ResponseCache struct {
+	Dir      string
+	TTL      time.Duration
+	MaxBytes int64
+}
+
+type cacheEntry struct {
+	CreatedAt time.Time `json:"created_at"`
+	Provider  string    `json:"provider"`
+	Response  string    `json:"response"`
+}
+
+func NewResponseCache() *ResponseCache {
+	cfg := config.Load().ResponseCache
+	if cfg.Disabled {
+		return nil
+	}
+
+	c := &ResponseCache{
+		Dir:      filepath.Join(config.GetConfigDir(), "cache"),
+		TTL:      defaultCacheTTL,
+		MaxBytes: defaultCacheMaxBytes,
+	}
+	if cfg.TTLHours > 0 {
+		c.TTL = time.Duration(cfg.TTLHours) * time.Hour
+	}
+	if cfg.MaxSizeMB > 0 {
+		c.MaxBytes = int64(cfg.MaxSizeMB) << 20
+	}
+	return c
+}
+
+func cacheKey(provider, system string, options GenerationOptions, format *Schema, messages []Message) string {
+	var opts *GenerationOptions
+	if !options.IsZero() {
+		opts = &options
+	}
+	payload, _ := json.Marshal(struct {
+		Provider string             `json:"provider"`
+		System   string             `json:"system"`
+		Options  *GenerationOptions `json:"options,omitempty"`
+		Format   *Schema            `json:"format,omitempty"`
+		Messages []Message          `json:"messages"`
+	}{provider, system, opts, format, messages})
+
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *ResponseCache) path(key string) string {
+	return filepath.Join(c.Dir, key+".json")
+}
+
+func (c *ResponseCache) Get(key string) (string, bool) {
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || time.Since(entry.CreatedAt) > c.TTL {
+		os.Remove(path)
+		return "", false
+	}
+
+	// The modification time doubles as the last-used time for eviction.
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return entry.Response, true
+}
+
+func (c *ResponseCache) Put(key, provider, response string) error {
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(cacheEntry{CreatedAt: time.Now(), Provider: provider, Response: response})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.path(key), data, 0644); err != nil {
+		return err
+	}
+	return c.evict()
+}
+
+func (c *ResponseCache) evict() error {
+	entries, err := os.ReadDir(c.Dir)
+	if err != nil {
+		return err
+	}
+
+	type cached struct {
+		path   string
+		size   int64
+		usedAt time.Time
+	}
+
+	var files []cached
+	var total int64
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(c.Dir, e.Name())
+		if time.Since(info.ModTime()) > c.TTL {
+			os.Remove(path)
+			continue
+		}
+		files = append(files, cached{
+			path:   path,
+			size:   info.Size(),
+			usedAt: info.ModTime(),
+		})
+		total += info.Size()
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].usedAt.Before(files[j].usedAt) })
+
+	for _, f := range files {
+		if total <= c.MaxBytes {
+			break
+		}
+		if os.Remove(f.path) == nil {
+			total -= f.size
+		}
+	}
+	return nil
+}
+
+type CachedProvider struct {
+	Provider
+	Cache *ResponseCache
+
+	system  string
+	tools   []Tool
+	pending []Image
+	options GenerationOptions
+	format  *Schema
+}
+
+func WithCache(p Provider, cache *ResponseCache) Provider {
+	if cache == nil {
+		return p
+	}
+	return &CachedProvider{Provider: p, Cache: cache}
+}
+
+func (c *CachedProvider) SetSystemPrompt(prompt string) {
+	c.system = prompt
+	c.Provider.SetSystemPrompt(prompt)
+}
+
+// Answers that used tools depend on the workspace at the time, so they are
+// never cached.
+func (c *CachedProvider) SetTools(tools []Tool) {
+	c.tools = tools
+	c.Provider.SetTools(tools)
+}
+
+func (c *CachedProvider) SetOptions(opts GenerationOptions) {
+	c.options = opts
+	c.Provider.SetOptions(opts)
+}
+
+func (c *CachedProvider) SetResponseFormat(schema *Schema) {
+	c.format = schema
+	c.Provider.SetResponseFormat(schema)
+}
+
+func (c *CachedProvider) Attach(images ...Image) {
+	c.pending = append(c.pending, images...)
+}
+
+// lookup hands any attached images on to the provider when there is no
+// cached answer; they are part of the key either way.
+func (c *CachedProvider) lookup(prompt string) (string, string, bool) {
+	images := c.pending
+	c.pending = nil
+
+	history := c.Provider.Conversation()
+	messages := append(history, Message{Role: "user", Content: prompt, Images: images})
+	key := cacheKey(c.Provider.Name(), c.system, c.options, c.format, messages)
+
+	resp, ok := c.Cache.Get(key)
+	if ok {
+		c.Provider.SetConversation(append(messages, Message{Role: "assistant", Content: resp}))
+	} else {
+		c.Provider.Attach(images...)
+	}
+	return key, resp, ok
+}
+
+// put leaves out replies that don't match the requested schema. SendJSON is
+// about to ask for a correction, and a rerun should not replay the broken
+// reply and pay for that correction again.
+func (c *CachedProvider) put(key, resp string) {
+	if c.format != nil {
+		var value interface{}
+		if _, err := decodeJSON(resp, *c.format, &value); err != nil {
+			return
+		}
+	}
+	c.Cache.Put(key, c.Provider.Name(), resp)
+}
+
+func (c *CachedProvider) Send(ctx context.Context, prompt string) (string, error) {
+	if len(c.tools) > 0 {
+		c.Provider.Attach(c.pending...)
+		c.pending = nil
+		return c.Provider.Send(ctx, prompt)
+	}
+	key, resp, ok := c.lookup(prompt)
+	if ok {
+		return resp, nil
+	}
+
+	resp, err := c.Provider.Send(ctx, prompt)
+	if err == nil {
+		c.put(key, resp)
+	}
+	return resp, err
+}
+
+func (c *CachedProvider) Stream(ctx context.Context, prompt string, onToken func(string)) (string, error) {
+	if len(c.tools) > 0 {
+		c.Provider.Attach(c.pending...)
+		c.pending = nil
+		return c.Provider.Stream(ctx, prompt, onToken)
+	}
+	key, resp, ok := c.lookup(prompt)
+	if ok {
+		onToken(resp)
+		return resp, nil
+	}
+
+	resp, err := c.Provider.Stream(ctx, prompt, onToken)
+	if err == nil {
+		c.put(key, resp)
+	}
+	return resp, err
+}
@@ -0,0 +1,39 @@
+package ai_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/broman0x/forgeai-cli/internal/ai"
+	"github.com/broman0x/forgeai-cli/internal/ai/aitest"
+)
+
+func TestCacheSkipsRepliesThatFailTheSchema(t *testing.T) {
+	aitest.Isolate(t)
+	cache := &ai.ResponseCache{Dir: t.TempDir(), TTL: time.Hour, MaxBytes: 1 << 20}
+
+	first := aitest.NewFakeProvider(`{"ok": "yes"}`, `{"ok": true, "count": 2}`)
+	var v verdict
+	if err := ai.SendJSON(context.Background(), ai.WithCache(first, cache), "judge", verdictSchema, &v); err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Calls()) != 2 {
+		t.Fatalf("calls = %d, want the invalid reply corrected once", len(first.Calls()))
+	}
+
+	// A rerun must ask the model again rather than replay the invalid reply.
+	rerun := aitest.NewFakeProvider(`{"ok": true, "count": 3}`)
+	if err := ai.SendJSON(context.Background(), ai.WithCache(rerun, cache), "judge", verdictSchema, &v); err != nil {
+		t.Fatal(err)
+	}
+	if calls := rerun.Calls(); len(calls) != 1 || calls[0].Prompt != "judge" || v.Count != 3 {
+		t.Fatalf("calls = %+v, verdict %+v", calls, v)
+	}
+
+	// A valid reply is cached as before.
+	again := aitest.NewFakeProvider()
+	if err := ai.SendJSON(context.Background(), ai.WithCache(again, cache), "judge", verdictSchema, &v); err != nil || len(again.Calls()) != 0 || v.Count != 3 {
+		t.Fatalf("valid reply was not served from the cache: %d calls, %+v, %v", len(again.Calls()), v, err)
+	}
+}
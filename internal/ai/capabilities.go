@@ -0,0 +1,211 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/broman0x/forgeai-cli/internal/config"
+)
+
+const defaultContextWindow = 8192
+
+// ModelInfo is what ForgeAI knows about a model. ContextWindow and MaxOutput
+// are in tokens, MaxOutput is 0 when unknown, and Price is nil for models
+// with no listed price (local models among them).
+type ModelInfo struct {
+	ContextWindow int
+	MaxOutput     int
+	Vision        bool
+	Tools         bool
+	Reasoning     bool
+	Price         *Price
+	Known         bool
+}
+
+type capability uint8
+
+const (
+	capVision capability = 1 << iota
+	capTools
+	capReasoning
+)
+
+type modelSpec struct {
+	context, output int
+	caps            capability
+	input, outPrice float64
+}
+
+// Built-in models, matched by longest model-name prefix. Prices are USD per
+// million tokens. The "models" config entry adds to and corrects this table.
+var modelSpecs = map[string]modelSpec{
+	"gpt-5":         {400000, 128000, capVision | capTools | capReasoning, 1.25, 10.00},
+	"gpt-5-mini":    {400000, 128000, capVision | capTools | capReasoning, 0.25, 2.00},
+	"gpt-4.1":       {1047576, 32768, capVision | capTools, 2.00, 8.00},
+	"gpt-4.1-mini":  {1047576, 32768, capVision | capTools, 0.40, 1.60},
+	"gpt-4.1-nano":  {1047576, 32768, capVision | capTools, 0.10, 0.40},
+	"gpt-4o":        {128000, 16384, capVision | capTools, 2.50, 10.00},
+	"gpt-4o-mini":   {128000, 16384, capVision | capTools, 0.15, 0.60},
+	"chatgpt-4o":    {128000, 16384, capVision, 0, 0},
+	"gpt-4-turbo":   {128000, 4096, capVision | capTools, 10.00, 30.00},
+	"gpt-4-vision":  {128000, 4096, capVision, 10.00, 30.00},
+	"gpt-4":         {8192, 8192, capTools, 30.00, 60.00},
+	"gpt-3.5-turbo": {16385, 4096, capTools, 0.50, 1.50},
+	"o1":            {200000, 100000, capVision | capTools | capReasoning, 15.00, 60.00},
+	"o1-mini":       {128000, 65536, 0, 1.10, 4.40},
+	"o3":            {200000, 100000, capVision | capTools | capReasoning, 2.00, 8.00},
+	"o3-mini":       {200000, 100000, capTools | capReasoning, 1.10, 4.40},
+	"o4":            {200000, 100000, capVision | capTools | capReasoning, 0, 0},
+	"o4-mini":       {200000, 100000, capVision | capTools | capReasoning, 1.10, 4.40},
+
+	"text-embedding-3-small": {8191, 0, 0, 0.02, 0},
+	"text-embedding-3-large": {8191, 0, 0, 0.13, 0},
+
+	"claude":            {200000, 4096, capTools, 0, 0},
+	"claude-3":          {200000, 4096, capVision | capTools, 0, 0},
+	"claude-3-haiku":    {200000, 4096, capVision | capTools, 0.25, 1.25},
+	"claude-3-sonnet":   {200000, 4096, capVision | capTools, 3.00, 15.00},
+	"claude-3-opus":     {200000, 4096, capVision | capTools, 15.00, 75.00},
+	"claude-3-5":        {200000, 8192, capVision | capTools, 0, 0},
+	"claude-3-5-haiku":  {200000, 8192, capVision | capTools, 0.80, 4.00},
+	"claude-3-5-sonnet": {200000, 8192, capVision | capTools, 3.00, 15.00},
+	"claude-3-7":        {200000, 64000, capVision | capTools | capReasoning, 0, 0},
+	"claude-3-7-sonnet": {200000, 64000, capVision | capTools | capReasoning, 3.00, 15.00},
+	"claude-sonnet-4":   {200000, 64000, capVision | capTools | capReasoning, 3.00, 15.00},
+	"claude-haiku-4":    {200000, 64000, capVision | capTools | capReasoning, 1.00, 5.00},
+	"claude-opus-4":     {200000, 32000, capVision | capTools | capReasoning, 15.00, 75.00},
+
+	"gemini-pro":        {32760, 8192, capTools, 0, 0},
+	"gemini-pro-vision": {12288, 4096, capVision, 0, 0},
+	"gemini-exp":        {1048576, 8192, capVision | capTools, 0, 0},
+	"gemini-1.5":        {1048576, 8192, capVision | capTools, 0, 0},
+	"gemini-1.5-pro":    {1048576, 8192, capVision | capTools, 1.25, 5.00},
+	"gemini-1.5-flash":  {1048576, 8192, capVision | capTools, 0.075, 0.30},
+	"gemini-2":          {1048576, 8192, capVision | capTools, 0, 0},
+	"gemini-2.0-flash":  {1048576, 8192, capVision | capTools, 0.10, 0.40},
+	"gemini-2.5-pro":    {1048576, 65536, capVision | capTools | capReasoning, 1.25, 10.00},
+	"gemini-2.5-flash":  {1048576, 65536, capVision | capTools | capReasoning, 0.30, 2.50},
+
+	"llama3":            {8192, 0, 0, 0, 0},
+	"llama3.1":          {131072, 0, capTools, 0, 0},
+	"llama3.2":          {131072, 0, capTools, 0, 0},
+	"llama3.2-vision":   {131072, 0, capVision, 0, 0},
+	"llama4":            {131072, 0, capVision | capTools, 0, 0},
+	"qwen2.5":           {32768, 0, capTools, 0, 0},
+	"qwen2.5vl":         {32768, 0, capVision, 0, 0},
+	"qwen2-vl":          {32768, 0, capVision, 0, 0},
+	"mistral":           {32768, 0, capTools, 0, 0},
+	"mistral-small3":    {32768, 0, capVision | capTools, 0, 0},
+	"deepseek-coder":    {16384, 0, 0, 0, 0},
+	"deepseek-r1":       {131072, 0, 0, 0, 0},
+	"codellama":         {16384, 0, 0, 0, 0},
+	"gemma":             {8192, 0, 0, 0, 0},
+	"gemma3":            {131072, 0, capVision, 0, 0},
+	"phi3":              {4096, 0, 0, 0, 0},
+	"llava":             {4096, 0, capVision, 0, 0},
+	"bakllava":          {4096, 0, capVision, 0, 0},
+	"moondream":         {2048, 0, capVision, 0, 0},
+	"minicpm-v":         {32768, 0, capVision, 0, 0},
+	"granite3.2-vision": {16384, 0, capVision, 0, 0},
+}
+
+func longestPrefix[V any](table map[string]V, model string) (string, bool) {
+	best, found := "", false
+	for prefix := range table {
+		if strings.HasPrefix(model, strings.ToLower(prefix)) && (!found || len(prefix) > len(best)) {
+			best, found = prefix, true
+		}
+	}
+	return best, found
+}
+
+// LookupModel resolves model against the built-in table and then the
+// "models" config entry, whose set fields win. Unknown models get an 8K
+// context window and are assumed to take tools but not images. Known is set
+// once the window comes from the table or from config.
+func LookupModel(model string) ModelInfo {
+	model = strings.ToLower(model)
+	info := ModelInfo{ContextWindow: defaultContextWindow, Tools: true}
+
+	if prefix, ok := longestPrefix(modelSpecs, model); ok {
+		spec := modelSpecs[prefix]
+		info = ModelInfo{
+			ContextWindow: spec.context,
+			MaxOutput:     spec.output,
+			Vision:        spec.caps&capVision != 0,
+			Tools:         spec.caps&capTools != 0,
+			Reasoning:     spec.caps&capReasoning != 0,
+			Known:         true,
+		}
+		if spec.input > 0 || spec.outPrice > 0 {
+			info.Price = &Price{spec.input, spec.outPrice}
+		}
+	}
+
+	custom := config.Load().Models
+	if prefix, ok := longestPrefix(custom, model); ok {
+		c := custom[prefix]
+		if c.ContextWindow > 0 {
+			info.ContextWindow = c.ContextWindow
+			info.Known = true
+		}
+		if c.MaxOutput > 0 {
+			info.MaxOutput = c.MaxOutput
+		}
+		if c.Vision != nil {
+			info.Vision = *c.Vision
+		}
+		if c.Tools != nil {
+			info.Tools = *c.Tools
+		}
+		if c.Reasoning != nil {
+			info.Reasoning = *c.Reasoning
+		}
+		if c.InputPrice != nil || c.OutputPrice != nil {
+			price := Price{}
+			if info.Price != nil {
+				price = *info.Price
+			}
+			if c.InputPrice != nil {
+				price.Input = *c.InputPrice
+			}
+			if c.OutputPrice != nil {
+				price.Output = *c.OutputPrice
+			}
+			info.Price = &price
+		}
+	}
+	return info
+}
+
+func ContextWindow(model string) int {
+	return LookupModel(model).ContextWindow
+}
+
+// PromptFit is the estimated size of a prompt against the model's window.
+type PromptFit struct {
+	Tokens int
+	Window int
+	Known  bool
+}
+
+// Tight reports a prompt that fits but leaves little room for the answer.
+func (f PromptFit) Tight() bool {
+	return f.Tokens > f.Window*4/5
+}
+
+// CheckPrompt estimates prompt plus images against model's context window
+// before anything is sent. It fails with a ContextLengthError only when the
+// model is known; an unknown model's window is a guess, so callers should
+// just warn.
+func CheckPrompt(model, prompt string, images int) (PromptFit, error) {
+	info := LookupModel(model)
+	fit := PromptFit{Tokens: EstimateTokens(prompt) + images*imageTokens, Window: info.ContextWindow, Known: info.Known}
+	if fit.Known && fit.Tokens > fit.Window {
+		return fit, &ContextLengthError{&APIError{
+			Provider: "forgeai",
+			Message:  fmt.Sprintf("the prompt is about %d tokens but %s takes at most %d", fit.Tokens, model, fit.Window),
+		}}
+	}
+	return fit, nil
+}
@@ -0,0 +1,93 @@
+package ai
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/broman0x/forgeai-cli/internal/config"
+)
+
+func TestLookupModelBuiltins(t *testing.T) {
+	isolateNetwork(t, config.NetworkConfig{})
+
+	tests := []struct {
+		model  string
+		window int
+		output int
+		vision bool
+		tools  bool
+		priced bool
+	}{
+		{"gpt-4o-mini-2024-07-18", 128000, 16384, true, true, true},
+		{"o1-mini", 128000, 65536, false, false, true},
+		{"claude-3-5-sonnet-latest", 200000, 8192, true, true, true},
+		{"claude-3-opus-20240229", 200000, 4096, true, true, true},
+		{"gemini-2.5-flash", 1048576, 65536, true, true, true},
+		{"llama3:latest", 8192, 0, false, false, false},
+		{"llama3.2-vision", 131072, 0, true, false, false},
+	}
+	for _, tt := range tests {
+		info := LookupModel(tt.model)
+		if !info.Known || info.ContextWindow != tt.window || info.MaxOutput != tt.output ||
+			info.Vision != tt.vision || info.Tools != tt.tools || (info.Price != nil) != tt.priced {
+			t.Errorf("%s: got %+v", tt.model, info)
+		}
+	}
+
+	unknown := LookupModel("some-local-model")
+	if unknown.Known || unknown.ContextWindow != defaultContextWindow || !unknown.Tools || unknown.Vision {
+		t.Errorf("unknown model: got %+v", unknown)
+	}
+	if got := claudeMaxTokens("claude-2.1"); got != defaultClaudeMaxTokens {
+		t.Errorf("claude-2.1 max tokens: got %d", got)
+	}
+}
+
+func TestLookupModelConfigOverrides(t *testing.T) {
+	isolateNetwork(t, config.NetworkConfig{})
+	yes, price := true, 0.5
+	config.Save(&config.Config{Models: map[string]config.ModelConfig{
+		"llama3":    {ContextWindow: 65536, Vision: &yes},
+		"my-model":  {ContextWindow: 32000, MaxOutput: 4000, OutputPrice: &price},
+		"gpt-4o-mi": {ContextWindow: 1000},
+	}})
+	config.ResetCache()
+
+	if info := LookupModel("llama3:8b"); info.ContextWindow != 65536 || !info.Vision || info.Tools {
+		t.Errorf("llama3 override: got %+v", info)
+	}
+	info := LookupModel("my-model-v2")
+	if !info.Known || info.ContextWindow != 32000 || info.MaxOutput != 4000 || info.Price == nil || info.Price.Output != 0.5 {
+		t.Errorf("custom model: got %+v", info)
+	}
+	// Unset fields keep the built-in values.
+	if info := LookupModel("gpt-4o-mini"); info.ContextWindow != 1000 || info.MaxOutput != 16384 || info.Price.Input != 0.15 {
+		t.Errorf("partial override: got %+v", info)
+	}
+}
+
+func TestCheckPrompt(t *testing.T) {
+	isolateNetwork(t, config.NetworkConfig{})
+	big := strings.Repeat("x", 4*10000)
+
+	_, err := CheckPrompt("phi3", big, 0)
+	var ctxErr *ContextLengthError
+	if !errors.As(err, &ctxErr) {
+		t.Fatalf("phi3 with 10K tokens: got %v", err)
+	}
+
+	// An unknown model's window is a guess, so it is not refused.
+	fit, err := CheckPrompt("some-local-model", big, 0)
+	if err != nil || fit.Known || fit.Tokens <= fit.Window {
+		t.Fatalf("unknown model: %+v, %v", fit, err)
+	}
+
+	fit, err = CheckPrompt("gpt-4", strings.Repeat("x", 4*7000), 0)
+	if err != nil || !fit.Tight() {
+		t.Fatalf("gpt-4 with 7K of 8K tokens should fit but be tight: %+v, %v", fit, err)
+	}
+	if fit, _ := CheckPrompt("gpt-4o", "hello", 2); fit.Tight() || fit.Tokens != 2+2*imageTokens {
+		t.Fatalf("small prompt: %+v", fit)
+	}
+}
@@ -0,0 +1,147 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+type ChainProvider struct {
+	Providers  []Provider
+	OnFailover func(from, to Provider, err error)
+
+	active  int
+	pending []Image
+}
+
+func NewChainProvider(providers ...Provider) *ChainProvider {
+	return &ChainProvider{Providers: providers}
+}
+
+func (c *ChainProvider) Active() Provider {
+	return c.Providers[c.active]
+}
+
+func (c *ChainProvider) Name() string {
+	return c.Active().Name()
+}
+
+func (c *ChainProvider) ModelName() string {
+	return c.Active().ModelName()
+}
+
+func (c *ChainProvider) Reset() {
+	for _, p := range c.Providers {
+		p.Reset()
+	}
+}
+
+func (c *ChainProvider) SetSystemPrompt(prompt string) {
+	for _, p := range c.Providers {
+		p.SetSystemPrompt(prompt)
+	}
+}
+
+func (c *ChainProvider) SetTools(tools []Tool) {
+	for _, p := range c.Providers {
+		p.SetTools(tools)
+	}
+}
+
+func (c *ChainProvider) SetOptions(opts GenerationOptions) {
+	for _, p := range c.Providers {
+		p.SetOptions(opts)
+	}
+}
+
+func (c *ChainProvider) SetResponseFormat(schema *Schema) {
+	for _, p := range c.Providers {
+		p.SetResponseFormat(schema)
+	}
+}
+
+// Attach holds the images until try knows which backend will get them.
+func (c *ChainProvider) Attach(images ...Image) {
+	c.pending = append(c.pending, images...)
+}
+
+func (c *ChainProvider) Conversation() []Message {
+	return c.Active().Conversation()
+}
+
+func (c *ChainProvider) SetConversation(messages []Message) {
+	c.Active().SetConversation(messages)
+}
+
+func (c *ChainProvider) Send(ctx context.Context, prompt string) (string, error) {
+	return c.try(ctx, func(p Provider) (string, bool, error) {
+		ans, err := p.Send(ctx, prompt)
+		return ans, false, err
+	})
+}
+
+func (c *ChainProvider) Stream(ctx context.Context, prompt string, onToken func(string)) (string, error) {
+	return c.try(ctx, func(p Provider) (string, bool, error) {
+		started := false
+		ans, err := p.Stream(ctx, prompt, func(token string) {
+			started = true
+			onToken(token)
+		})
+		return ans, started, err
+	})
+}
+
+// try starts with the backend that answered last and walks the rest of the
+// chain in order. Once a backend has produced output we stop, otherwise the
+// user would see two half answers.
+func (c *ChainProvider) try(ctx context.Context, call func(Provider) (string, bool, error)) (string, error) {
+	if len(c.Providers) == 0 {
+		return "", fmt.Errorf("fallback chain is empty")
+	}
+
+	history := c.Active().Conversation()
+	images := c.pending
+	c.pending = nil
+	var lastErr error
+
+	for n := 0; n < len(c.Providers); n++ {
+		i := (c.active + n) % len(c.Providers)
+		p := c.Providers[i]
+		if i != c.active {
+			p.SetConversation(history)
+		}
+		p.Attach(images...)
+
+		ans, started, err := call(p)
+		if err == nil {
+			c.active = i
+			return ans, nil
+		}
+		if started || ctx.Err() != nil || !canFailOver(err) {
+			return "", err
+		}
+
+		lastErr = err
+		if next := c.Providers[(i+1)%len(c.Providers)]; n+1 < len(c.Providers) && c.OnFailover != nil {
+			c.OnFailover(p, next, err)
+		}
+	}
+
+	return "", lastErr
+}
+
+// canFailOver reports errors the next backend may well not have: an
+// exhausted quota or rate limit, an outage, a timeout or a dropped
+// connection. Other errors, a 400 for a bad request, a prompt that is too
+// long or an untrusted certificate among them, would only fail again or hide
+// a misconfigured endpoint.
+func canFailOver(err error) bool {
+	var (
+		quota       *QuotaError
+		rateLimit   *RateLimitError
+		unavailable *UnavailableError
+		timeout     *TimeoutError
+	)
+	return errors.As(err, &quota) || errors.As(err, &rateLimit) || errors.As(err, &unavailable) ||
+		errors.As(err, &timeout) || isTransientNetError(err)
+}
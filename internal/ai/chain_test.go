@@ -0,0 +1,113 @@
+package ai_test
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/broman0x/forgeai-cli/internal/ai"
+	"github.com/broman0x/forgeai-cli/internal/ai/aitest"
+)
+
+func failing(model string, err error) *aitest.FakeProvider {
+	return &aitest.FakeProvider{Model: model, Replies: []aitest.Reply{{Err: err}}}
+}
+
+func fake(model string, replies ...string) *aitest.FakeProvider {
+	f := aitest.NewFakeProvider(replies...)
+	f.Model = model
+	return f
+}
+
+func TestChainFailsOverOnQuotaRegardlessOfStatus(t *testing.T) {
+	// Anthropic reports an empty credit balance as a 400.
+	broke := failing("claude", &ai.QuotaError{APIError: &ai.APIError{Provider: "claude", StatusCode: 400, Message: "Your credit balance is too low"}})
+	limited := failing("gemini", &ai.RateLimitError{APIError: &ai.APIError{Provider: "gemini", StatusCode: 429}})
+	local := fake("llama3", "from ollama")
+
+	var hops []string
+	chain := ai.NewChainProvider(broke, limited, local)
+	chain.OnFailover = func(from, to ai.Provider, err error) {
+		hops = append(hops, from.ModelName()+"->"+to.ModelName())
+	}
+
+	ans, err := chain.Send(context.Background(), "hi")
+	if err != nil || ans != "from ollama" {
+		t.Fatalf("got %q, %v", ans, err)
+	}
+	if strings.Join(hops, ",") != "claude->gemini,gemini->llama3" {
+		t.Fatalf("failover order %v", hops)
+	}
+	if chain.Active() != local {
+		t.Fatal("the backend that answered should stay active")
+	}
+}
+
+func TestChainDoesNotFailOverOnRequestErrors(t *testing.T) {
+	for _, err := range []error{
+		&ai.AuthError{APIError: &ai.APIError{Provider: "openai", StatusCode: 401}},
+		&ai.ContextLengthError{APIError: &ai.APIError{Provider: "openai", StatusCode: 400}},
+		&ai.APIError{Provider: "openai", StatusCode: 400, Message: "bad request"},
+		&url.Error{Op: "Post", URL: "https://llm.internal/v1", Err: x509.UnknownAuthorityError{}},
+	} {
+		next := fake("next", "unused")
+		chain := ai.NewChainProvider(failing("first", err), next)
+		if _, got := chain.Send(context.Background(), "hi"); !errors.Is(got, err) {
+			t.Fatalf("got %v, want %v", got, err)
+		}
+		if len(next.Calls()) != 0 {
+			t.Fatalf("%v should not fail over", err)
+		}
+	}
+}
+
+func TestChainCarriesHistoryAndImagesToTheNextBackend(t *testing.T) {
+	first := fake("first", "first answer")
+	second := fake("second", "second answer")
+	chain := ai.NewChainProvider(first, second)
+	if _, err := chain.Send(context.Background(), "one"); err != nil {
+		t.Fatal(err)
+	}
+
+	first.Replies = []aitest.Reply{{Err: &ai.UnavailableError{APIError: &ai.APIError{Provider: "first", StatusCode: 503}}}}
+	img := ai.Image{Name: "shot.png", MIMEType: "image/png", Data: []byte("png")}
+	chain.Attach(img)
+	if _, err := chain.Send(context.Background(), "two"); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := second.Calls()
+	if len(calls) != 1 {
+		t.Fatalf("calls %+v", calls)
+	}
+	if h := calls[0].History; len(h) != 2 || h[0].Content != "one" || h[1].Content != "first answer" {
+		t.Fatalf("history not carried over: %+v", h)
+	}
+	if len(calls[0].Images) != 1 || calls[0].Images[0].Name != "shot.png" {
+		t.Fatalf("images did not reach the backend that answered: %+v", calls[0].Images)
+	}
+}
+
+// halfStream sends one token, then fails.
+type halfStream struct{ *aitest.FakeProvider }
+
+func (h halfStream) Stream(ctx context.Context, prompt string, onToken func(string)) (string, error) {
+	onToken("Partial ")
+	return "", &ai.UnavailableError{APIError: &ai.APIError{Provider: "half", StatusCode: 529}}
+}
+
+func TestChainStopsOnceTokensHaveStreamed(t *testing.T) {
+	next := fake("next", "unused")
+	chain := ai.NewChainProvider(halfStream{fake("half")}, next)
+	chain.OnFailover = func(from, to ai.Provider, err error) { t.Fatal("no failover after output") }
+
+	var out string
+	_, err := chain.Stream(context.Background(), "hi", func(tok string) { out += tok })
+	var unavailable *ai.UnavailableError
+	if !errors.As(err, &unavailable) || out != "Partial " || len(next.Calls()) != 0 {
+		t.Fatalf("err %v, output %q, next calls %d", err, out, len(next.Calls()))
+	}
+}
@@ -0,0 +1,252 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/broman0x/forgeai-cli/internal/config"
+)
+
+// Embedder turns texts into vectors, one per text and in the same order.
+// OpenAI (and compatible servers), Gemini and Ollama implement it.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	Name() string
+	ModelName() string
+}
+
+// OpenAI-compatible servers have no model in common, so they need an
+// explicit "embedding" entry.
+var defaultEmbeddingModels = map[string]string{
+	"openai": "text-embedding-3-small",
+	"gemini": "gemini-embedding-001",
+	"ollama": "nomic-embed-text",
+}
+
+// NewEmbedder picks the embedding provider from the "embedding" config
+// entry, then the last chat provider when it has an embeddings API, then
+// whichever of Ollama, Gemini and OpenAI is available.
+func NewEmbedder() (Embedder, error) {
+	cfg := config.Load()
+	if cfg.Embedding.Provider != "" {
+		return CreateEmbedder(cfg.Embedding.Provider, cfg.Embedding.Model)
+	}
+	if _, ok := defaultEmbeddingModels[canonicalProvider(cfg.LastProvider)]; ok {
+		if emb, err := CreateEmbedder(cfg.LastProvider, ""); err == nil {
+			return emb, nil
+		}
+	}
+	if isOllamaRunning() {
+		return CreateEmbedder("ollama", "")
+	}
+	if os.Getenv("GEMINI_API_KEY") != "" {
+		return CreateEmbedder("gemini", "")
+	}
+	if os.Getenv("OPENAI_API_KEY") != "" {
+		return CreateEmbedder("openai", "")
+	}
+	return nil, fmt.Errorf("no embedding provider available. Set up Gemini, OpenAI or Ollama (Claude has no embeddings API)")
+}
+
+// EmbedderProvider returns the provider type CreateEmbedder takes to build
+// emb again, or "" for embedders it cannot build.
+func EmbedderProvider(emb Embedder) string {
+	if p, ok := emb.(interface{ providerType() string }); ok {
+		return p.providerType()
+	}
+	return ""
+}
+
+func canonicalProvider(pType string) string {
+	switch strings.ToLower(pType) {
+	case "chatgpt":
+		return "openai"
+	case "compatible", "custom":
+		return "openai-compatible"
+	case "anthropic":
+		return "claude"
+	}
+	return strings.ToLower(pType)
+}
+
+func CreateEmbedder(pType, model string) (Embedder, error) {
+	pType = canonicalProvider(pType)
+	if model == "" {
+		model = defaultEmbeddingModels[pType]
+	}
+
+	if pType == "openai-compatible" && model == "" {
+		return nil, fmt.Errorf("openai-compatible needs an embedding model; set \"embedding\": {\"provider\": \"openai-compatible\", \"model\": \"...\"} in config.json")
+	}
+
+	switch pType {
+	case "gemini", "openai", "openai-compatible":
+		prov, err := CreateProvider(pType, model)
+		if err != nil {
+			return nil, err
+		}
+		emb, ok := prov.(Embedder)
+		if !ok {
+			return nil, fmt.Errorf("%s has no embeddings API", pType)
+		}
+		return emb, nil
+	case "ollama":
+		// CreateProvider would check for the chat model; embedding models
+		// are reported missing by the embed call itself.
+		if !isOllamaRunning() {
+			return nil, fmt.Errorf("ollama is not running")
+		}
+		return newOllamaProvider(model), nil
+	case "claude":
+		return nil, fmt.Errorf("claude has no embeddings API; set \"embedding\": {\"provider\": ...} in config.json")
+	}
+	return nil, fmt.Errorf("unknown provider type: %s", pType)
+}
+
+// postJSON sends one JSON request and decodes a 200 reply into out. Error
+// bodies come as {"error": "..."} or {"error": {"message": "..."}}.
+func postJSON(ctx context.Context, client *http.Client, retry RetryPolicy, provider, url string, headers map[string]string, body, out interface{}) error {
+	payload, _ := json.Marshal(body)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := retry.Do(client, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		var res struct {
+			Error json.RawMessage `json:"error"`
+		}
+		if json.Unmarshal(data, &res) == nil && len(res.Error) > 0 {
+			var msg string
+			var obj struct {
+				Message string `json:"message"`
+			}
+			if json.Unmarshal(res.Error, &msg) == nil && msg != "" {
+				return &APIError{Provider: provider, StatusCode: resp.StatusCode, Message: msg}
+			}
+			if json.Unmarshal(res.Error, &obj) == nil && obj.Message != "" {
+				return &APIError{Provider: provider, StatusCode: resp.StatusCode, Message: obj.Message}
+			}
+		}
+		return newAPIError(provider, resp.StatusCode, data)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("parse error: %s", string(data))
+	}
+	return nil
+}
+
+func checkEmbeddings(provider string, got, want int) error {
+	if got != want {
+		return fmt.Errorf("%s error: got %d embeddings for %d inputs", provider, got, want)
+	}
+	return nil
+}
+
+func (o *OpenAIProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	headers := map[string]string{}
+	if o.ApiKey != "" {
+		headers["Authorization"] = "Bearer " + o.ApiKey
+	}
+	for k, v := range o.Headers {
+		headers[k] = v
+	}
+
+	var res struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Usage *openAIUsage `json:"usage,omitempty"`
+	}
+	url := strings.TrimSuffix(o.BaseURL, "/chat/completions") + "/embeddings"
+	body := map[string]interface{}{"model": o.Model, "input": texts}
+	if err := postJSON(withTokens(ctx, estimateTexts(texts)), o.Client, o.Retry, o.providerType(), url, headers, body, &res); err != nil {
+		return nil, classifyError(o.providerType(), err)
+	}
+	if err := checkEmbeddings(o.providerType(), len(res.Data), len(texts)); err != nil {
+		return nil, err
+	}
+	if res.Usage != nil {
+		recordUsage(ctx, o.providerType(), o.Model, Usage{InputTokens: res.Usage.PromptTokens})
+	}
+
+	vectors := make([][]float32, len(texts))
+	for i, d := range res.Data {
+		if d.Index >= 0 && d.Index < len(vectors) {
+			vectors[d.Index] = d.Embedding
+		} else {
+			vectors[i] = d.Embedding
+		}
+	}
+	return vectors, nil
+}
+
+func (g *GeminiProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	type request struct {
+		Model   string        `json:"model"`
+		Content geminiContent `json:"content"`
+	}
+	requests := make([]request, len(texts))
+	for i, text := range texts {
+		requests[i] = request{Model: "models/" + g.Model, Content: geminiContent{Parts: []geminiPart{{Text: text}}}}
+	}
+
+	var res struct {
+		Embeddings []struct {
+			Values []float32 `json:"values"`
+		} `json:"embeddings"`
+	}
+	url := fmt.Sprintf("%s%s:batchEmbedContents", geminiBaseURL, g.Model)
+	headers := map[string]string{"x-goog-api-key": g.ApiKey}
+	if err := postJSON(withTokens(ctx, estimateTexts(texts)), g.Client, g.Retry, "gemini", url, headers, map[string]interface{}{"requests": requests}, &res); err != nil {
+		return nil, classifyError("gemini", err)
+	}
+	if err := checkEmbeddings("gemini", len(res.Embeddings), len(texts)); err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]float32, len(texts))
+	for i, e := range res.Embeddings {
+		vectors[i] = e.Values
+	}
+	return vectors, nil
+}
+
+func (o *OllamaProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	var res struct {
+		Embeddings      [][]float32 `json:"embeddings"`
+		PromptEvalCount int         `json:"prompt_eval_count"`
+	}
+	body := map[string]interface{}{"model": o.Model, "input": texts}
+	if err := postJSON(withTokens(ctx, estimateTexts(texts)), o.Client, o.Retry, "ollama", o.apiURL("/api/embed"), nil, body, &res); err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			return nil, &ModelNotFoundError{Provider: "ollama", Model: o.Model}
+		}
+		return nil, classifyError("ollama", err)
+	}
+	if err := checkEmbeddings("ollama", len(res.Embeddings), len(texts)); err != nil {
+		return nil, err
+	}
+	recordUsage(ctx, "ollama", o.Model, Usage{InputTokens: res.PromptEvalCount})
+	return res.Embeddings, nil
+}
@@ -0,0 +1,75 @@
+package ai_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/broman0x/forgeai-cli/internal/ai"
+	"github.com/broman0x/forgeai-cli/internal/ai/aitest"
+)
+
+func TestOpenAIEmbedKeepsInputOrder(t *testing.T) {
+	aitest.Isolate(t)
+	t.Setenv("OPENAI_API_KEY", "test-key")
+	capture := &captureTransport{reply: `{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}],"usage":{"prompt_tokens":4}}`}
+	prev := ai.Transport
+	ai.Transport = capture
+	t.Cleanup(func() { ai.Transport = prev })
+
+	emb, err := ai.CreateEmbedder("openai", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vectors, err := emb.Embed(context.Background(), []string{"first", "second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vectors[0][0] != 1 || vectors[1][1] != 1 {
+		t.Fatalf("vectors out of order: %v", vectors)
+	}
+	if body := capture.bodies[0]; !strings.Contains(body, `"model":"text-embedding-3-small"`) || !strings.Contains(body, `"input":["first","second"]`) {
+		t.Fatalf("unexpected request: %s", body)
+	}
+}
+
+func TestGeminiEmbedBatchesRequests(t *testing.T) {
+	aitest.Isolate(t)
+	t.Setenv("GEMINI_API_KEY", "test-key")
+	capture := &captureTransport{reply: `{"embeddings":[{"values":[0.5]},{"values":[0.25]}]}`}
+	prev := ai.Transport
+	ai.Transport = capture
+	t.Cleanup(func() { ai.Transport = prev })
+
+	emb, err := ai.CreateEmbedder("gemini", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vectors, err := emb.Embed(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != 2 || vectors[1][0] != 0.25 {
+		t.Fatalf("got %v", vectors)
+	}
+	if body := capture.bodies[0]; strings.Count(body, `"model":"models/gemini-embedding-001"`) != 2 {
+		t.Fatalf("unexpected request: %s", body)
+	}
+
+	if _, err := ai.CreateEmbedder("claude", ""); err == nil {
+		t.Fatal("claude should have no embedder")
+	}
+}
+
+func TestCompatibleEmbedderNeedsAModel(t *testing.T) {
+	home := aitest.Isolate(t)
+	writeConfig(t, home, `{"openai_compatible": {"base_url": "http://localhost:8080/v1"}}`)
+
+	if _, err := ai.CreateEmbedder("openai-compatible", ""); err == nil || !strings.Contains(err.Error(), `"embedding"`) {
+		t.Fatalf("expected a config hint, got %v", err)
+	}
+	emb, err := ai.CreateEmbedder("openai-compatible", "bge-small")
+	if err != nil || emb.ModelName() != "bge-small" {
+		t.Fatalf("got %v, %v", emb, err)
+	}
+}
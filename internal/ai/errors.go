@@ -0,0 +1,175 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/broman0x/forgeai-cli/internal/redact"
+)
+
+// APIError is a failed request to a provider. StatusCode is 0 when the
+// request never got an HTTP response; Err then holds the transport error.
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Code       string
+	Message    string
+	Err        error
+}
+
+func (e *APIError) Unwrap() error { return e.Err }
+
+func (e *APIError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("%s error: %s", e.Provider, redact.String(e.Message))
+	}
+	return fmt.Sprintf("%s error %d: %s", e.Provider, e.StatusCode, redact.String(e.Message))
+}
+
+func newAPIError(provider string, status int, body []byte) *APIError {
+	return &APIError{Provider: provider, StatusCode: status, Message: strings.TrimSpace(string(body))}
+}
+
+// The typed errors below wrap an *APIError, so errors.As works both for the
+// specific kind and for *APIError itself.
+type (
+	AuthError           struct{ *APIError }
+	RateLimitError      struct{ *APIError }
+	QuotaError          struct{ *APIError }
+	ContextLengthError  struct{ *APIError }
+	ContentBlockedError struct{ *APIError }
+	TimeoutError        struct{ *APIError }
+	UnavailableError    struct{ *APIError }
+)
+
+func (e *AuthError) Unwrap() error           { return e.APIError }
+func (e *RateLimitError) Unwrap() error      { return e.APIError }
+func (e *QuotaError) Unwrap() error          { return e.APIError }
+func (e *ContextLengthError) Unwrap() error  { return e.APIError }
+func (e *ContentBlockedError) Unwrap() error { return e.APIError }
+func (e *TimeoutError) Unwrap() error        { return e.APIError }
+func (e *UnavailableError) Unwrap() error    { return e.APIError }
+
+func contentFiltered(provider string) error {
+	return &ContentBlockedError{&APIError{Provider: provider, Code: "content_filter", Message: "the response was blocked by the provider's content filter"}}
+}
+
+// Message fragments the providers use for conditions they don't give a
+// distinct status or error code for.
+var contextHints = []string{"context_length_exceeded", "maximum context length", "prompt is too long", "exceeds the maximum number of tokens", "input token count", "too many tokens", "context window"}
+
+// Error codes and types that mark a rejected key, an exhausted quota or
+// blocked content. They are matched whole, so a parameter named
+// safety_settings in a bad request doesn't read as a safety block.
+var (
+	authCodes    = []string{"invalid_api_key", "authentication_error", "permission_error", "api_key_invalid", "permission_denied", "unauthenticated"}
+	quotaCodes   = []string{"insufficient_quota", "billing_hard_limit_reached", "billing_not_active"}
+	blockedCodes = []string{"content_filter", "content_policy_violation", "safety", "blocklist", "prohibited_content", "spii", "recitation"}
+)
+
+func containsAny(s string, hints []string) bool {
+	s = strings.ToLower(s)
+	for _, h := range hints {
+		if strings.Contains(s, h) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasCode(code string, codes []string) bool {
+	code = strings.ToLower(code)
+	for _, c := range codes {
+		if code == c {
+			return true
+		}
+	}
+	return false
+}
+
+// isQuota reports an exhausted quota or credit balance. Claude has no code
+// for it, only a 400 with a fixed message; Gemini sends the same 429
+// for every limit and names the daily ones in the message.
+func isQuota(e *APIError) bool {
+	switch {
+	case hasCode(e.Code, quotaCodes):
+		return true
+	case e.StatusCode == http.StatusBadRequest:
+		return strings.Contains(strings.ToLower(e.Message), "credit balance is too low")
+	case e.StatusCode == http.StatusTooManyRequests:
+		return containsAny(e.Message, []string{"perday", "per day"})
+	}
+	return false
+}
+
+// classifyError turns whatever a provider call returned into one of the typed
+// errors above. Errors it cannot place are passed through with any secrets in
+// their message redacted.
+func classifyError(provider string, err error) error {
+	if err == nil || isTyped(err) {
+		return err
+	}
+	if errors.Is(err, context.Canceled) {
+		return redact.Error(err)
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		switch {
+		case isTimeout(err):
+			return &TimeoutError{&APIError{Provider: provider, Message: "request timed out", Err: err}}
+		case isTransientNetError(err):
+			return &UnavailableError{&APIError{Provider: provider, Message: err.Error(), Err: err}}
+		}
+		return redact.Error(err)
+	}
+
+	text := apiErr.Code + " " + apiErr.Message
+	switch status := apiErr.StatusCode; {
+	case isQuota(apiErr):
+		return &QuotaError{apiErr}
+	case status == http.StatusUnauthorized || status == http.StatusForbidden || hasCode(apiErr.Code, authCodes):
+		return &AuthError{apiErr}
+	case status == http.StatusTooManyRequests:
+		return &RateLimitError{apiErr}
+	case status == http.StatusRequestEntityTooLarge || containsAny(text, contextHints):
+		return &ContextLengthError{apiErr}
+	case hasCode(apiErr.Code, blockedCodes):
+		return &ContentBlockedError{apiErr}
+	case status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout:
+		return &TimeoutError{apiErr}
+	case status >= 500:
+		return &UnavailableError{apiErr}
+	}
+	return apiErr
+}
+
+func isTyped(err error) bool {
+	var (
+		auth        *AuthError
+		rateLimit   *RateLimitError
+		quota       *QuotaError
+		ctxLen      *ContextLengthError
+		blocked     *ContentBlockedError
+		timeout     *TimeoutError
+		unavailable *UnavailableError
+	)
+	return errors.As(err, &auth) || errors.As(err, &rateLimit) || errors.As(err, &quota) ||
+		errors.As(err, &ctxLen) || errors.As(err, &blocked) || errors.As(err, &timeout) ||
+		errors.As(err, &unavailable)
+}
+
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode != 0 {
+		return isRetryableStatus(apiErr.StatusCode)
+	}
+	return isTransientNetError(err)
+}
@@ -0,0 +1,488 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const geminiBaseURL = "https://generativelanguage.googleapis.com/v1beta/models/"
+
+type GeminiProvider struct {
+	ApiKey  string
+	Model   string
+	System  string
+	Client  *http.Client
+	Retry   RetryPolicy
+	History []geminiContent
+
+	tools   []Tool
+	pending []Image
+	options GenerationOptions
+	format  *Schema
+}
+
+type geminiRequest struct {
+	SystemInstruction *geminiContent   `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent  `json:"contents"`
+	Tools             []geminiTool     `json:"tools,omitempty"`
+	GenerationConfig  *geminiGenConfig `json:"generationConfig,omitempty"`
+}
+type geminiGenConfig struct {
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"topP,omitempty"`
+	MaxOutputTokens int      `json:"maxOutputTokens,omitempty"`
+	StopSequences   []string `json:"stopSequences,omitempty"`
+
+	ThinkingConfig *geminiThinkingConfig `json:"thinkingConfig,omitempty"`
+
+	ResponseMimeType string                 `json:"responseMimeType,omitempty"`
+	ResponseSchema   map[string]interface{} `json:"responseSchema,omitempty"`
+}
+type geminiThinkingConfig struct {
+	ThinkingBudget  int  `json:"thinkingBudget"`
+	IncludeThoughts bool `json:"includeThoughts"`
+}
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+	InlineData       *geminiBlob             `json:"inline_data,omitempty"`
+	Thought          bool                    `json:"thought,omitempty"`
+	ThoughtSignature string                  `json:"thoughtSignature,omitempty"`
+}
+type geminiBlob struct {
+	MimeType string `json:"mime_type"`
+	Data     string `json:"data"`
+}
+type geminiFunctionCall struct {
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+type geminiFunctionResponse struct {
+	Name     string            `json:"name"`
+	Response map[string]string `json:"response"`
+}
+type geminiTool struct {
+	FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+}
+type geminiFunctionDeclaration struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+type geminiResponse struct {
+	Candidates []struct {
+		Content struct {
+			Parts []geminiPart `json:"parts"`
+		} `json:"content"`
+		FinishReason string `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback,omitempty"`
+	UsageMetadata *struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		ThoughtsTokenCount   int `json:"thoughtsTokenCount"`
+	} `json:"usageMetadata,omitempty"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+		Details []struct {
+			Reason string `json:"reason"`
+		} `json:"details"`
+	} `json:"error,omitempty"`
+}
+
+// apiError prefers the ErrorInfo reason, such as API_KEY_INVALID, over the
+// status, which is a generic INVALID_ARGUMENT for most bad requests.
+func (r *geminiResponse) apiError() *APIError {
+	code := r.Error.Status
+	for _, d := range r.Error.Details {
+		if d.Reason != "" {
+			code = d.Reason
+			break
+		}
+	}
+	return &APIError{Provider: "gemini", StatusCode: r.Error.Code, Code: code, Message: r.Error.Message}
+}
+
+// blocked reports a prompt or answer stopped by Gemini's safety filters,
+// which comes back as a normal 200 response.
+func (r *geminiResponse) blocked() error {
+	reason := ""
+	if r.PromptFeedback != nil {
+		reason = r.PromptFeedback.BlockReason
+	}
+	if reason == "" && len(r.Candidates) > 0 {
+		switch r.Candidates[0].FinishReason {
+		case "SAFETY", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "RECITATION":
+			reason = r.Candidates[0].FinishReason
+		}
+	}
+	if reason == "" {
+		return nil
+	}
+	return &ContentBlockedError{&APIError{Provider: "gemini", Code: reason, Message: "blocked by safety filters (" + strings.ToLower(reason) + ")"}}
+}
+
+func newGeminiProvider(apiKey, model string) *GeminiProvider {
+	return &GeminiProvider{
+		ApiKey:  apiKey,
+		Model:   model,
+		Client:  newHTTPClient("gemini", 120*time.Second),
+		Retry:   retryPolicyFromConfig("gemini", apiKey),
+		History: []geminiContent{},
+	}
+}
+
+func (g *GeminiProvider) Name() string      { return "Gemini (" + g.Model + ")" }
+func (g *GeminiProvider) ModelName() string { return g.Model }
+func (g *GeminiProvider) Reset()            { g.History = []geminiContent{} }
+
+func (g *GeminiProvider) providerType() string {
+	return "gemini"
+}
+
+func (g *GeminiProvider) SetSystemPrompt(prompt string) {
+	g.System = prompt
+}
+
+func (g *GeminiProvider) SetTools(tools []Tool) {
+	g.tools = tools
+}
+
+func (g *GeminiProvider) SetOptions(opts GenerationOptions) {
+	g.options = opts
+}
+
+func (g *GeminiProvider) SetResponseFormat(schema *Schema) {
+	g.format = schema
+}
+
+func (g *GeminiProvider) generationConfig() *geminiGenConfig {
+	opts := g.options
+	if opts.IsZero() && g.format == nil {
+		return nil
+	}
+	cfg := &geminiGenConfig{Temperature: opts.Temperature, TopP: opts.TopP, MaxOutputTokens: opts.MaxTokens, StopSequences: opts.Stop}
+	if budget := opts.thinkingBudget(); budget > 0 && LookupModel(g.Model).Reasoning {
+		cfg.ThinkingConfig = &geminiThinkingConfig{ThinkingBudget: budget, IncludeThoughts: true}
+	}
+	if g.format != nil {
+		cfg.ResponseMimeType = "application/json"
+		cfg.ResponseSchema = g.format.Schema
+	}
+	return cfg
+}
+
+func (g *GeminiProvider) Attach(images ...Image) {
+	g.pending = append(g.pending, images...)
+}
+
+func (g *GeminiProvider) takeImages() ([]Image, error) {
+	images := g.pending
+	g.pending = nil
+	return images, checkImages("gemini", g.Model, images)
+}
+
+func geminiUserContent(text string, images []Image) geminiContent {
+	parts := []geminiPart{{Text: text}}
+	for _, img := range images {
+		parts = append(parts, geminiPart{InlineData: &geminiBlob{MimeType: img.MIMEType, Data: img.base64()}})
+	}
+	return geminiContent{Role: "user", Parts: parts}
+}
+
+func geminiTools(tools []Tool) []geminiTool {
+	if len(tools) == 0 {
+		return nil
+	}
+	var decls []geminiFunctionDeclaration
+	for _, t := range tools {
+		decls = append(decls, geminiFunctionDeclaration{Name: t.Name, Description: t.Description, Parameters: t.Parameters})
+	}
+	return []geminiTool{{FunctionDeclarations: decls}}
+}
+
+// geminiTurn splits model parts into text and function calls, leaving out
+// thought summaries. Gemini has no call ids, so the function name doubles as
+// one.
+func geminiTurn(parts []geminiPart) (string, []ToolCall) {
+	var sb strings.Builder
+	var calls []ToolCall
+	for _, part := range parts {
+		if !part.Thought {
+			sb.WriteString(part.Text)
+		}
+		if part.FunctionCall != nil {
+			calls = append(calls, ToolCall{ID: part.FunctionCall.Name, Name: part.FunctionCall.Name, Arguments: part.FunctionCall.Args})
+		}
+	}
+	return sb.String(), calls
+}
+
+// appendGeminiToolResults echoes the model turn unchanged, since thinking
+// models reject function calls whose thoughtSignature was dropped.
+func appendGeminiToolResults(contents []geminiContent, turn []geminiPart, calls []ToolCall, results []string) []geminiContent {
+	var out []geminiPart
+	for i, call := range calls {
+		out = append(out, geminiPart{FunctionResponse: &geminiFunctionResponse{
+			Name:     call.Name,
+			Response: map[string]string{"result": results[i]},
+		}})
+	}
+	return append(contents,
+		geminiContent{Role: "model", Parts: turn},
+		geminiContent{Role: "user", Parts: out})
+}
+
+func (g *GeminiProvider) Conversation() []Message {
+	messages := make([]Message, len(g.History))
+	for i, c := range g.History {
+		role := c.Role
+		if role == "model" {
+			role = "assistant"
+		}
+		var sb strings.Builder
+		var images []Image
+		for _, part := range c.Parts {
+			sb.WriteString(part.Text)
+			if part.InlineData == nil {
+				continue
+			}
+			if data, err := base64.StdEncoding.DecodeString(part.InlineData.Data); err == nil {
+				images = append(images, Image{MIMEType: part.InlineData.MimeType, Data: data})
+			}
+		}
+		messages[i] = Message{Role: role, Content: sb.String(), Images: images}
+	}
+	return messages
+}
+
+func (g *GeminiProvider) SetConversation(messages []Message) {
+	g.History = make([]geminiContent, len(messages))
+	for i, m := range messages {
+		if m.Role == "assistant" {
+			g.History[i] = geminiContent{Role: "model", Parts: []geminiPart{{Text: m.Content}}}
+			continue
+		}
+		g.History[i] = geminiUserContent(m.Content, m.Images)
+	}
+}
+
+func geminiTokens(req geminiRequest) int {
+	contents := req.Contents
+	if req.SystemInstruction != nil {
+		contents = append([]geminiContent{*req.SystemInstruction}, contents...)
+	}
+	total := 0
+	for _, c := range contents {
+		for _, part := range c.Parts {
+			total += EstimateTokens(part.Text)
+			if part.InlineData != nil {
+				total += imageTokens
+			}
+			if part.FunctionCall != nil {
+				total += EstimateTokens(string(part.FunctionCall.Args))
+			}
+			if part.FunctionResponse != nil {
+				total += EstimateTokens(part.FunctionResponse.Response["result"])
+			}
+		}
+	}
+	return total
+}
+
+func (g *GeminiProvider) newRequest(ctx context.Context, contents []geminiContent, stream bool) *http.Request {
+	url := fmt.Sprintf("%s%s:generateContent", geminiBaseURL, g.Model)
+	if stream {
+		url = fmt.Sprintf("%s%s:streamGenerateContent?alt=sse", geminiBaseURL, g.Model)
+	}
+
+	reqBody := geminiRequest{Contents: contents, Tools: geminiTools(g.tools), GenerationConfig: g.generationConfig()}
+	if g.System != "" {
+		reqBody.SystemInstruction = &geminiContent{Parts: []geminiPart{{Text: g.System}}}
+	}
+	payload, _ := json.Marshal(reqBody)
+
+	req, _ := http.NewRequestWithContext(withTokens(ctx, geminiTokens(reqBody)), "POST", url, bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", g.ApiKey)
+	return req
+}
+
+// text returns either the answer text or the thought summaries.
+func (r *geminiResponse) text(thought bool) string {
+	var sb strings.Builder
+	if len(r.Candidates) > 0 {
+		for _, part := range r.Candidates[0].Content.Parts {
+			if part.Thought == thought {
+				sb.WriteString(part.Text)
+			}
+		}
+	}
+	return sb.String()
+}
+
+func (r *geminiResponse) usage() Usage {
+	if r.UsageMetadata == nil {
+		return Usage{}
+	}
+	// Thinking is billed as output.
+	m := r.UsageMetadata
+	return Usage{InputTokens: m.PromptTokenCount, OutputTokens: m.CandidatesTokenCount + m.ThoughtsTokenCount}
+}
+
+func (g *GeminiProvider) Send(ctx context.Context, prompt string) (string, error) {
+	images, err := g.takeImages()
+	if err != nil {
+		return "", err
+	}
+	userMsg := geminiUserContent(prompt, images)
+	currentContext := append(g.History, userMsg)
+	contents := append([]geminiContent(nil), currentContext...)
+
+	var turn []geminiPart
+	ans, err := toolLoop(ctx, g.tools, func() (string, []ToolCall, error) {
+		parts, err := g.complete(ctx, contents)
+		turn = parts
+		text, calls := geminiTurn(parts)
+		return text, calls, err
+	}, func(calls []ToolCall, results []string) {
+		contents = appendGeminiToolResults(contents, turn, calls, results)
+	})
+	if err != nil {
+		return "", classifyError("gemini", err)
+	}
+
+	ans = strings.TrimSpace(ans)
+	g.History = append(currentContext, geminiContent{Role: "model", Parts: []geminiPart{{Text: ans}}})
+	return ans, nil
+}
+
+func (g *GeminiProvider) complete(ctx context.Context, contents []geminiContent) ([]geminiPart, error) {
+	resp, err := g.Retry.Do(g.Client, g.newRequest(ctx, contents, false))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+
+	var res geminiResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, newAPIError("gemini", resp.StatusCode, body)
+		}
+		return nil, fmt.Errorf("parse error: %s", string(body))
+	}
+
+	if res.Error != nil {
+		return nil, res.apiError()
+	}
+
+	recordUsage(ctx, "gemini", g.Model, res.usage())
+
+	if err := res.blocked(); err != nil {
+		return nil, err
+	}
+	emitThinking(ctx, res.text(true))
+	if len(res.Candidates) == 0 || len(res.Candidates[0].Content.Parts) == 0 {
+		return nil, fmt.Errorf("empty response")
+	}
+	return res.Candidates[0].Content.Parts, nil
+}
+
+func (g *GeminiProvider) Stream(ctx context.Context, prompt string, onToken func(string)) (string, error) {
+	images, err := g.takeImages()
+	if err != nil {
+		return "", err
+	}
+	userMsg := geminiUserContent(prompt, images)
+	currentContext := append(g.History, userMsg)
+	contents := append([]geminiContent(nil), currentContext...)
+
+	var turn []geminiPart
+	ans, err := toolLoop(ctx, g.tools, func() (string, []ToolCall, error) {
+		parts, err := g.streamOnce(ctx, contents, onToken)
+		turn = parts
+		text, calls := geminiTurn(parts)
+		if text != "" && len(calls) > 0 {
+			onToken("\n\n")
+		}
+		return text, calls, err
+	}, func(calls []ToolCall, results []string) {
+		contents = appendGeminiToolResults(contents, turn, calls, results)
+	})
+	if err != nil {
+		return "", classifyError("gemini", err)
+	}
+
+	ans = strings.TrimSpace(ans)
+	if ans == "" {
+		return "", fmt.Errorf("empty response")
+	}
+	g.History = append(currentContext, geminiContent{Role: "model", Parts: []geminiPart{{Text: ans}}})
+	return ans, nil
+}
+
+func (g *GeminiProvider) streamOnce(ctx context.Context, contents []geminiContent, onToken func(string)) ([]geminiPart, error) {
+	resp, err := g.Retry.Do(g.Client, g.newRequest(ctx, contents, true))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		var res geminiResponse
+		if json.Unmarshal(body, &res) == nil && res.Error != nil {
+			return nil, res.apiError()
+		}
+		return nil, newAPIError("gemini", resp.StatusCode, body)
+	}
+
+	var parts []geminiPart
+	var tokens Usage
+	err = readSSE(resp.Body, func(_ string, data []byte) error {
+		var chunk geminiResponse
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return fmt.Errorf("parse error: %s", string(data))
+		}
+		if chunk.Error != nil {
+			return chunk.apiError()
+		}
+		if err := chunk.blocked(); err != nil {
+			return err
+		}
+		if chunk.UsageMetadata != nil {
+			tokens = chunk.usage()
+		}
+		if len(chunk.Candidates) > 0 {
+			parts = append(parts, chunk.Candidates[0].Content.Parts...)
+		}
+		emitThinking(ctx, chunk.text(true))
+		if text := chunk.text(false); text != "" {
+			onToken(text)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	recordUsage(ctx, "gemini", g.Model, tokens)
+	return parts, nil
+}
@@ -0,0 +1,204 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/broman0x/forgeai-cli/internal/config"
+)
+
+const (
+	maxHistoryBudget = 64000
+	// Local, compatible and plugin models ForgeAI has no window for mostly
+	// take 32K or more; halving the 8K guess would summarize after a few
+	// turns. context_window under "models" in config.json sets the real one.
+	unknownHistoryBudget = 16000
+	summaryPrefix        = "Summary of our earlier conversation:\n"
+)
+
+const summarySystemPrompt = `You compress chat transcripts. Summarize the conversation you are given so it can replace the original turns.
+Keep every decision, requirement, file name, code identifier and open question. Drop greetings and repetition.
+Reply with the summary only, in the language the conversation used.`
+
+// EstimateTokens uses the usual four-bytes-per-token rule of thumb; it only
+// has to be close enough to stay clear of the context limit.
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+func estimateMessage(m Message) int {
+	return EstimateTokens(m.Content) + len(m.Images)*imageTokens + 4
+}
+
+func estimateMessages(messages []Message) int {
+	total := 0
+	for _, m := range messages {
+		total += estimateMessage(m)
+	}
+	return total
+}
+
+func HistoryBudget(model string) int {
+	if budget := config.Load().HistoryBudgetTokens; budget > 0 {
+		return budget
+	}
+	info := LookupModel(model)
+	if !info.Known {
+		return unknownHistoryBudget
+	}
+	budget := info.ContextWindow / 2
+	if budget > maxHistoryBudget {
+		budget = maxHistoryBudget
+	}
+	return budget
+}
+
+// HistoryManager keeps a provider's conversation inside a token budget. When
+// a new prompt would overflow it, the oldest turns are folded into a summary
+// written by the model itself; Reset still clears everything.
+type HistoryManager struct {
+	Provider
+	Budget int
+
+	system  string
+	tools   []Tool
+	pending []Image
+	options GenerationOptions
+	format  *Schema
+}
+
+func WithHistoryBudget(p Provider) Provider {
+	return &HistoryManager{Provider: p}
+}
+
+func (h *HistoryManager) budget() int {
+	if h.Budget > 0 {
+		return h.Budget
+	}
+	return HistoryBudget(h.Provider.ModelName())
+}
+
+func (h *HistoryManager) SetSystemPrompt(prompt string) {
+	h.system = prompt
+	h.Provider.SetSystemPrompt(prompt)
+}
+
+func (h *HistoryManager) SetTools(tools []Tool) {
+	h.tools = tools
+	h.Provider.SetTools(tools)
+}
+
+func (h *HistoryManager) SetOptions(opts GenerationOptions) {
+	h.options = opts
+	h.Provider.SetOptions(opts)
+}
+
+func (h *HistoryManager) SetResponseFormat(schema *Schema) {
+	h.format = schema
+	h.Provider.SetResponseFormat(schema)
+}
+
+// Attach waits for compact to finish so the summary request does not pick
+// up the user's images.
+func (h *HistoryManager) Attach(images ...Image) {
+	h.pending = append(h.pending, images...)
+}
+
+func (h *HistoryManager) takeImages() {
+	h.Provider.Attach(h.pending...)
+	h.pending = nil
+}
+
+func (h *HistoryManager) Send(ctx context.Context, prompt string) (string, error) {
+	if err := h.compact(ctx, prompt); err != nil {
+		h.pending = nil
+		return "", err
+	}
+	h.takeImages()
+	return h.Provider.Send(ctx, prompt)
+}
+
+func (h *HistoryManager) Stream(ctx context.Context, prompt string, onToken func(string)) (string, error) {
+	if err := h.compact(ctx, prompt); err != nil {
+		h.pending = nil
+		return "", err
+	}
+	h.takeImages()
+	return h.Provider.Stream(ctx, prompt, onToken)
+}
+
+func (h *HistoryManager) compact(ctx context.Context, prompt string) error {
+	history := h.Provider.Conversation()
+	budget := h.budget()
+	pending := EstimateTokens(prompt) + EstimateTokens(h.system) + len(h.pending)*imageTokens
+	if estimateMessages(history)+pending <= budget {
+		return nil
+	}
+
+	cut := splitRecent(history, budget/2-pending)
+	older, recent := history[:cut], history[cut:]
+	if len(older) == 0 {
+		return nil
+	}
+
+	summary, err := h.summarize(ctx, older)
+	if err != nil && ctx.Err() != nil {
+		return err
+	}
+
+	var compacted []Message
+	if err == nil && summary != "" {
+		compacted = append(compacted,
+			Message{Role: "user", Content: summaryPrefix + summary},
+			Message{Role: "assistant", Content: "Understood, I will keep that context in mind."})
+	}
+	h.Provider.SetConversation(append(compacted, recent...))
+	return nil
+}
+
+// splitRecent returns the index of the oldest user turn from which the rest
+// of the history still fits in budget, so turns are never split in half.
+func splitRecent(history []Message, budget int) int {
+	cut := len(history)
+	used := 0
+	for i := len(history) - 1; i >= 0; i-- {
+		used += estimateMessage(history[i])
+		if used > budget {
+			break
+		}
+		if history[i].Role == "user" {
+			cut = i
+		}
+	}
+	return cut
+}
+
+func (h *HistoryManager) summarize(ctx context.Context, older []Message) (string, error) {
+	saved := h.Provider.Conversation()
+	defer func() {
+		h.Provider.SetConversation(saved)
+		h.Provider.SetSystemPrompt(h.system)
+		h.Provider.SetTools(h.tools)
+		h.Provider.SetOptions(h.options)
+		h.Provider.SetResponseFormat(h.format)
+	}()
+
+	var sb strings.Builder
+	for _, m := range older {
+		fmt.Fprintf(&sb, "%s: %s\n\n", strings.ToUpper(m.Role), m.Content)
+	}
+	transcript := sb.String()
+	// Leave a quarter of the window for the instructions and the summary.
+	if limit := ContextWindow(h.Provider.ModelName()) * 3; len(transcript) > limit {
+		transcript = transcript[len(transcript)-limit:]
+	}
+
+	h.Provider.SetConversation(nil)
+	h.Provider.SetSystemPrompt(summarySystemPrompt)
+	h.Provider.SetTools(nil)
+	h.Provider.SetOptions(GenerationOptions{})
+	h.Provider.SetResponseFormat(nil)
+	summary, err := h.Provider.Send(ctx, transcript)
+	return strings.TrimSpace(summary), err
+}
@@ -0,0 +1,128 @@
+package ai_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/broman0x/forgeai-cli/internal/ai"
+	"github.com/broman0x/forgeai-cli/internal/ai/aitest"
+)
+
+func longTurns(n int) []ai.Message {
+	var msgs []ai.Message
+	for i := 0; i < n; i++ {
+		msgs = append(msgs,
+			ai.Message{Role: "user", Content: fmt.Sprintf("question %d %s", i, strings.Repeat("q", 200))},
+			ai.Message{Role: "assistant", Content: fmt.Sprintf("answer %d %s", i, strings.Repeat("a", 200))})
+	}
+	return msgs
+}
+
+func TestHistoryUnderBudgetIsLeftAlone(t *testing.T) {
+	aitest.Isolate(t)
+	fake := aitest.NewFakeProvider("ok")
+	fake.SetConversation(longTurns(2))
+
+	prov := &ai.HistoryManager{Provider: fake, Budget: 10000}
+	if _, err := prov.Send(context.Background(), "next"); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := fake.Calls()
+	if len(calls) != 1 || len(calls[0].History) != 4 {
+		t.Fatalf("calls = %+v, want one call with the full history", calls)
+	}
+}
+
+func TestHistoryOverBudgetIsSummarized(t *testing.T) {
+	aitest.Isolate(t)
+	fake := aitest.NewFakeProvider("the user asked ten questions", "fresh answer")
+	fake.SetConversation(longTurns(10))
+
+	prov := &ai.HistoryManager{Provider: fake, Budget: 600}
+	prov.SetSystemPrompt("be helpful")
+
+	ans, err := prov.Send(context.Background(), "next")
+	if err != nil || ans != "fresh answer" {
+		t.Fatalf("ans = %q, err = %v", ans, err)
+	}
+
+	calls := fake.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("calls = %d, want a summary call and the real call", len(calls))
+	}
+
+	summaryCall, realCall := calls[0], calls[1]
+	if !strings.HasPrefix(summaryCall.System, "You compress chat transcripts") || len(summaryCall.History) != 0 {
+		t.Fatalf("summary call = %+v", summaryCall)
+	}
+	if !strings.Contains(summaryCall.Prompt, "question 0") {
+		t.Fatal("oldest turn was not part of the summarised transcript")
+	}
+
+	if realCall.System != "be helpful" {
+		t.Fatalf("system prompt not restored: %q", realCall.System)
+	}
+	if !strings.Contains(realCall.History[0].Content, "the user asked ten questions") {
+		t.Fatalf("history does not start with the summary: %+v", realCall.History[0])
+	}
+	last := realCall.History[len(realCall.History)-1]
+	if !strings.HasPrefix(last.Content, "answer 9") {
+		t.Fatalf("most recent turn was dropped: %+v", last)
+	}
+	if len(realCall.History) >= 20 {
+		t.Fatalf("history was not trimmed: %d messages", len(realCall.History))
+	}
+}
+
+func TestHistoryDropsOldTurnsWhenSummaryFails(t *testing.T) {
+	aitest.Isolate(t)
+	fake := aitest.NewFakeProvider()
+	fake.Replies = []aitest.Reply{{Err: fmt.Errorf("overloaded")}, {Text: "fresh answer"}}
+	fake.SetConversation(longTurns(10))
+
+	prov := &ai.HistoryManager{Provider: fake, Budget: 600}
+	if _, err := prov.Send(context.Background(), "next"); err != nil {
+		t.Fatal(err)
+	}
+
+	realCall := fake.Calls()[1]
+	if strings.HasPrefix(realCall.History[0].Content, "Summary") || !strings.HasPrefix(realCall.History[0].Content, "question") {
+		t.Fatalf("unexpected first message: %+v", realCall.History[0])
+	}
+	if len(realCall.History) >= 20 {
+		t.Fatalf("history was not trimmed: %d messages", len(realCall.History))
+	}
+}
+
+func TestContextWindowUsesLongestPrefix(t *testing.T) {
+	if got := ai.ContextWindow("gpt-4o-mini"); got != 128000 {
+		t.Fatalf("gpt-4o-mini = %d", got)
+	}
+	if got := ai.ContextWindow("gpt-4-0613"); got != 8192 {
+		t.Fatalf("gpt-4-0613 = %d", got)
+	}
+	if got := ai.ContextWindow("some-local-model"); got != 8192 {
+		t.Fatalf("unknown model = %d", got)
+	}
+}
+
+func TestHistoryBudgetForUnknownModels(t *testing.T) {
+	home := aitest.Isolate(t)
+	if got := ai.HistoryBudget("phi3"); got != 2048 {
+		t.Fatalf("phi3 = %d, want half its 4K window", got)
+	}
+	if got := ai.HistoryBudget("some-local-model"); got != 16000 {
+		t.Fatalf("unknown model = %d, want the conservative default rather than half of 8K", got)
+	}
+
+	writeConfig(t, home, `{"models": {"some-local": {"context_window": 32768}, "other-local": {"vision": true}}}`)
+	if got := ai.HistoryBudget("some-local-model"); got != 16384 {
+		t.Fatalf("configured window = %d, want 16384", got)
+	}
+	if got := ai.HistoryBudget("other-local-model"); got != 16000 {
+		t.Fatalf("an entry without a window = %d, want the default", got)
+	}
+}
@@ -0,0 +1,87 @@
+package ai
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+const maxImageBytes = 20 * 1024 * 1024
+
+// imageTokens is a rough per-image cost used when budgeting history.
+const imageTokens = 1000
+
+var imageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+type Image struct {
+	Name     string `json:"name"`
+	MIMEType string `json:"mime_type"`
+	Data     []byte `json:"data"`
+}
+
+func LoadImage(path string) (Image, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Image{}, fmt.Errorf("image error: %v", err)
+	}
+	if info.Size() > maxImageBytes {
+		return Image{}, fmt.Errorf("image error: %s is larger than %d MB", path, maxImageBytes/1024/1024)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Image{}, fmt.Errorf("image error: %v", err)
+	}
+	mime := http.DetectContentType(data)
+	if !imageTypes[mime] {
+		return Image{}, fmt.Errorf("image error: %s is %s, expected PNG, JPEG, GIF or WebP", path, mime)
+	}
+	return Image{Name: filepath.Base(path), MIMEType: mime, Data: data}, nil
+}
+
+func (img Image) base64() string {
+	return base64.StdEncoding.EncodeToString(img.Data)
+}
+
+func (img Image) dataURL() string {
+	return "data:" + img.MIMEType + ";base64," + img.base64()
+}
+
+type ImagesUnsupportedError struct {
+	Provider string
+	Model    string
+}
+
+func (e *ImagesUnsupportedError) Error() string {
+	return fmt.Sprintf("%s model %q does not accept images; switch to a vision model such as %s", e.Provider, e.Model, visionSuggestion[e.Provider])
+}
+
+var visionSuggestion = map[string]string{
+	"openai": "gpt-4o",
+	"claude": "claude-3-5-sonnet-latest",
+	"gemini": "gemini-2.5-flash",
+	"ollama": "llava or llama3.2-vision",
+}
+
+// OpenAI-compatible servers are not checked; whatever they serve is left for
+// the server to reject.
+func SupportsImages(provider, model string) bool {
+	if provider == "openai-compatible" {
+		return true
+	}
+	return LookupModel(model).Vision
+}
+
+func checkImages(provider, model string, images []Image) error {
+	if len(images) > 0 && !SupportsImages(provider, model) {
+		return &ImagesUnsupportedError{Provider: provider, Model: model}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package ai_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/broman0x/forgeai-cli/internal/ai"
+	"github.com/broman0x/forgeai-cli/internal/ai/aitest"
+)
+
+// A 1x1 transparent PNG.
+var pngPixel = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00\x1f\x15\xc4\x89\x00\x00\x00\rIDATx\x9cc\x00\x01\x00\x00\x05\x00\x01\r\n-\xb4\x00\x00\x00\x00IEND\xaeB`\x82")
+
+// captureTransport answers with replies in turn, then reply for the rest.
+type captureTransport struct {
+	bodies  []string
+	reply   string
+	replies []string
+}
+
+func (c *captureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, _ := io.ReadAll(req.Body)
+	reply := c.reply
+	if n := len(c.bodies); n < len(c.replies) {
+		reply = c.replies[n]
+	}
+	c.bodies = append(c.bodies, string(body))
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(reply)),
+		Request:    req,
+	}, nil
+}
+
+func loadPixel(t *testing.T) ai.Image {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "pixel.png")
+	os.WriteFile(path, pngPixel, 0644)
+	img, err := ai.LoadImage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func TestLoadImageRejectsNonImages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	os.WriteFile(path, []byte("just text"), 0644)
+	if _, err := ai.LoadImage(path); err == nil {
+		t.Fatal("expected an error for a text file")
+	}
+}
+
+func TestOpenAISendsImageURL(t *testing.T) {
+	aitest.Isolate(t)
+	t.Setenv("OPENAI_API_KEY", "test-key")
+	capture := &captureTransport{reply: `{"choices":[{"message":{"role":"assistant","content":"A pixel."}}]}`}
+	prev := ai.Transport
+	ai.Transport = capture
+	t.Cleanup(func() { ai.Transport = prev })
+
+	prov, err := ai.CreateProvider("openai", "gpt-4o-mini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prov.Attach(loadPixel(t))
+	if _, err := prov.Send(context.Background(), "What is this?"); err != nil {
+		t.Fatal(err)
+	}
+
+	body := capture.bodies[0]
+	if !strings.Contains(body, `"type":"image_url"`) || !strings.Contains(body, `"url":"data:image/png;base64,`) {
+		t.Fatalf("request has no image part: %s", body)
+	}
+
+	// The image stays with its turn but is not sent again as a new attachment.
+	if _, err := prov.Send(context.Background(), "And now?"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(capture.bodies[1], `"type":"image_url"`) != 1 {
+		t.Fatalf("second request should carry the image once, in history: %s", capture.bodies[1])
+	}
+}
+
+func TestTextOnlyModelRejectsImages(t *testing.T) {
+	aitest.Isolate(t)
+	t.Setenv("OPENAI_API_KEY", "test-key")
+
+	prov, err := ai.CreateProvider("openai", "gpt-3.5-turbo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prov.Attach(loadPixel(t))
+
+	_, err = prov.Send(context.Background(), "What is this?")
+	var unsupported *ai.ImagesUnsupportedError
+	if !errors.As(err, &unsupported) || !strings.Contains(err.Error(), "gpt-4o") {
+		t.Fatalf("err = %v, want ImagesUnsupportedError suggesting a vision model", err)
+	}
+	if len(prov.Conversation()) != 0 {
+		t.Fatal("rejected turn leaked into history")
+	}
+}
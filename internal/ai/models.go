@@ -0,0 +1,256 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/broman0x/forgeai-cli/internal/config"
+	"github.com/broman0x/forgeai-cli/internal/redact"
+)
+
+const (
+	modelCacheTTL   = 24 * time.Hour
+	claudeModelsURL = "https://api.anthropic.com/v1/models"
+)
+
+var FallbackModels = map[string][]string{
+	"gemini": {"gemini-2.5-flash", "gemini-2.5-pro", "gemini-2.5-flash-lite", "gemini-2.0-flash"},
+	"openai": {"gpt-4o-mini", "gpt-4o", "gpt-4.1", "gpt-4.1-mini", "o4-mini", "gpt-3.5-turbo"},
+	"claude": {"claude-sonnet-4-20250514", "claude-3-5-haiku-20241022", "claude-3-7-sonnet-20250219", "claude-3-haiku-20240307"},
+}
+
+type ModelCatalog struct {
+	Provider  string
+	Models    []string
+	FetchedAt time.Time
+	Offline   bool
+}
+
+type modelCacheEntry struct {
+	FetchedAt time.Time `json:"fetched_at"`
+	Models    []string  `json:"models"`
+}
+
+func canonicalProviderType(pType string) string {
+	switch strings.ToLower(pType) {
+	case "chatgpt":
+		return "openai"
+	case "anthropic":
+		return "claude"
+	}
+	return strings.ToLower(pType)
+}
+
+func DiscoverModels(ctx context.Context, pType string) ModelCatalog {
+	pType = canonicalProviderType(pType)
+	cache := loadModelCache()
+
+	cached, hasCache := cache[pType]
+	hasCache = hasCache && len(cached.Models) > 0
+	if hasCache && time.Since(cached.FetchedAt) < modelCacheTTL {
+		return ModelCatalog{Provider: pType, Models: cached.Models, FetchedAt: cached.FetchedAt}
+	}
+
+	models, err := fetchModels(ctx, pType)
+	if err == nil && len(models) > 0 {
+		now := time.Now()
+		cache[pType] = modelCacheEntry{FetchedAt: now, Models: models}
+		saveModelCache(cache)
+		return ModelCatalog{Provider: pType, Models: models, FetchedAt: now}
+	}
+
+	if hasCache {
+		return ModelCatalog{Provider: pType, Models: cached.Models, FetchedAt: cached.FetchedAt, Offline: true}
+	}
+	return ModelCatalog{Provider: pType, Models: FallbackModels[pType], Offline: true}
+}
+
+func fetchModels(ctx context.Context, pType string) ([]string, error) {
+	prov, err := CreateProvider(pType, "")
+	if err != nil {
+		return nil, err
+	}
+
+	switch p := prov.(type) {
+	case *OpenAIProvider:
+		return p.listModels(ctx)
+	case *ClaudeProvider:
+		return p.listModels(ctx)
+	case *GeminiProvider:
+		return p.listModels(ctx)
+	case *PluginProvider:
+		return p.listModels(ctx)
+	}
+	return nil, fmt.Errorf("model discovery not supported for %s", pType)
+}
+
+func getModelCachePath() string {
+	return filepath.Join(config.GetConfigDir(), "models_cache.json")
+}
+
+func loadModelCache() map[string]modelCacheEntry {
+	cache := map[string]modelCacheEntry{}
+	data, err := os.ReadFile(getModelCachePath())
+	if err != nil {
+		return cache
+	}
+	json.Unmarshal(data, &cache)
+	return cache
+}
+
+func saveModelCache(cache map[string]modelCacheEntry) error {
+	path := getModelCachePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func getJSON(ctx context.Context, client *http.Client, retry RetryPolicy, rawURL string, headers map[string]string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := retry.Do(client, req)
+	if err != nil {
+		return redact.Error(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("list models error %d: %s", resp.StatusCode, redact.String(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func isOpenAIChatModel(id string) bool {
+	chat := false
+	for _, prefix := range []string{"gpt-", "chatgpt-", "o1", "o3", "o4"} {
+		if strings.HasPrefix(id, prefix) {
+			chat = true
+			break
+		}
+	}
+	if !chat {
+		return false
+	}
+	for _, skip := range []string{"instruct", "audio", "realtime", "transcribe", "tts", "image", "search", "embedding"} {
+		if strings.Contains(id, skip) {
+			return false
+		}
+	}
+	return true
+}
+
+func (o *OpenAIProvider) listModels(ctx context.Context) ([]string, error) {
+	headers := map[string]string{"Authorization": "Bearer " + o.ApiKey}
+	modelsURL := strings.TrimSuffix(o.BaseURL, "/chat/completions") + "/models"
+
+	var res struct {
+		Data []struct {
+			ID      string `json:"id"`
+			Created int64  `json:"created"`
+		} `json:"data"`
+	}
+	if err := getJSON(ctx, o.Client, o.Retry, modelsURL, headers, &res); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(res.Data, func(i, j int) bool { return res.Data[i].Created > res.Data[j].Created })
+
+	var models []string
+	for _, m := range res.Data {
+		if isOpenAIChatModel(m.ID) {
+			models = append(models, m.ID)
+		}
+	}
+	return models, nil
+}
+
+func (c *ClaudeProvider) listModels(ctx context.Context) ([]string, error) {
+	headers := map[string]string{
+		"x-api-key":         c.ApiKey,
+		"anthropic-version": "2023-06-01",
+	}
+
+	var models []string
+	afterID := ""
+	for {
+		q := url.Values{"limit": {"100"}}
+		if afterID != "" {
+			q.Set("after_id", afterID)
+		}
+
+		var res struct {
+			Data []struct {
+				ID string `json:"id"`
+			} `json:"data"`
+			HasMore bool   `json:"has_more"`
+			LastID  string `json:"last_id"`
+		}
+		if err := getJSON(ctx, c.Client, c.Retry, claudeModelsURL+"?"+q.Encode(), headers, &res); err != nil {
+			return nil, err
+		}
+		for _, m := range res.Data {
+			models = append(models, m.ID)
+		}
+		if !res.HasMore || res.LastID == "" {
+			return models, nil
+		}
+		afterID = res.LastID
+	}
+}
+
+func (g *GeminiProvider) listModels(ctx context.Context) ([]string, error) {
+	var models []string
+	pageToken := ""
+	for {
+		q := url.Values{"pageSize": {"1000"}}
+		if pageToken != "" {
+			q.Set("pageToken", pageToken)
+		}
+
+		var res struct {
+			Models []struct {
+				Name                       string   `json:"name"`
+				SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+			} `json:"models"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		listURL := strings.TrimSuffix(geminiBaseURL, "/") + "?" + q.Encode()
+		if err := getJSON(ctx, g.Client, g.Retry, listURL, map[string]string{"x-goog-api-key": g.ApiKey}, &res); err != nil {
+			return nil, err
+		}
+
+		for _, m := range res.Models {
+			for _, method := range m.SupportedGenerationMethods {
+				if method == "generateContent" {
+					models = append(models, strings.TrimPrefix(m.Name, "models/"))
+					break
+				}
+			}
+		}
+		if res.NextPageToken == "" {
+			return models, nil
+		}
+		pageToken = res.NextPageToken
+	}
+}
@@ -0,0 +1,343 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type OllamaProvider struct {
+	BaseURL string
+	Model   string
+	System  string
+	Client  *http.Client
+	Retry   RetryPolicy
+	History []ollamaMessage
+
+	tools []Tool
+	// noTools is set once the model has told us it cannot call tools, so
+	// chat keeps working on older local models.
+	noTools bool
+	pending []Image
+	vision  map[string]bool
+	options GenerationOptions
+	format  *Schema
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+	Images    []Image          `json:"-"`
+}
+
+func (m ollamaMessage) MarshalJSON() ([]byte, error) {
+	type plain ollamaMessage
+	var images []string
+	for _, img := range m.Images {
+		images = append(images, img.base64())
+	}
+	return json.Marshal(struct {
+		plain
+		Images []string `json:"images,omitempty"`
+	}{plain(m), images})
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Tools    []openAITool    `json:"tools,omitempty"`
+	Options  *ollamaOptions  `json:"options,omitempty"`
+	Format   interface{}     `json:"format,omitempty"`
+	Stream   bool            `json:"stream"`
+}
+
+type ollamaOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	NumPredict  int      `json:"num_predict,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+}
+
+type ollamaResponse struct {
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error,omitempty"`
+}
+
+func newOllamaProvider(model string) *OllamaProvider {
+	host := getOllamaHost()
+	port := getOllamaPort()
+	baseURL := fmt.Sprintf("http://%s:%s/api/chat", host, port)
+
+	return &OllamaProvider{
+		BaseURL: baseURL,
+		Model:   model,
+		Client:  newHTTPClient("ollama", 300*time.Second),
+		Retry:   retryPolicyFromConfig("ollama", ""),
+		History: []ollamaMessage{},
+	}
+}
+
+func (o *OllamaProvider) Name() string      { return "Ollama (" + o.Model + ")" }
+func (o *OllamaProvider) ModelName() string { return o.Model }
+func (o *OllamaProvider) Reset()            { o.History = []ollamaMessage{} }
+
+func (o *OllamaProvider) providerType() string {
+	return "ollama"
+}
+
+func (o *OllamaProvider) SetSystemPrompt(prompt string) {
+	o.System = prompt
+}
+
+func (o *OllamaProvider) SetTools(tools []Tool) {
+	o.tools = tools
+	o.noTools = false
+}
+
+func (o *OllamaProvider) SetOptions(opts GenerationOptions) {
+	o.options = opts
+}
+
+func (o *OllamaProvider) SetResponseFormat(schema *Schema) {
+	o.format = schema
+}
+
+func (o *OllamaProvider) requestOptions() *ollamaOptions {
+	opts := o.options
+	if opts.IsZero() {
+		return nil
+	}
+	return &ollamaOptions{Temperature: opts.Temperature, TopP: opts.TopP, NumPredict: opts.MaxTokens, Stop: opts.Stop}
+}
+
+func (o *OllamaProvider) Attach(images ...Image) {
+	o.pending = append(o.pending, images...)
+}
+
+func (o *OllamaProvider) takeImages(ctx context.Context) ([]Image, error) {
+	images := o.pending
+	o.pending = nil
+	if len(images) == 0 || o.acceptsImages(ctx) {
+		return images, nil
+	}
+	return nil, &ImagesUnsupportedError{Provider: "ollama", Model: o.Model}
+}
+
+// acceptsImages asks Ollama for the model's capabilities, falling back to
+// known vision model names on servers too old to report them.
+func (o *OllamaProvider) acceptsImages(ctx context.Context) bool {
+	if ok, known := o.vision[o.Model]; known {
+		return ok
+	}
+
+	ok := SupportsImages("ollama", o.Model)
+	if details, err := o.ShowModel(ctx, o.Model); err == nil && len(details.Capabilities) > 0 {
+		ok = false
+		for _, c := range details.Capabilities {
+			if c == "vision" {
+				ok = true
+			}
+		}
+	}
+
+	if o.vision == nil {
+		o.vision = map[string]bool{}
+	}
+	o.vision[o.Model] = ok
+	return ok
+}
+
+func (o *OllamaProvider) activeTools() []Tool {
+	if o.noTools {
+		return nil
+	}
+	return o.tools
+}
+
+func ollamaToolCalls(calls []ollamaToolCall) []ToolCall {
+	out := make([]ToolCall, len(calls))
+	for i, c := range calls {
+		out[i] = ToolCall{ID: c.Function.Name, Name: c.Function.Name, Arguments: c.Function.Arguments}
+	}
+	return out
+}
+
+func appendOllamaToolResults(messages []ollamaMessage, turn ollamaMessage, calls []ToolCall, results []string) []ollamaMessage {
+	messages = append(messages, turn)
+	for i, call := range calls {
+		messages = append(messages, ollamaMessage{Role: "tool", ToolName: call.Name, Content: results[i]})
+	}
+	return messages
+}
+
+func isToolsUnsupported(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest &&
+		strings.Contains(apiErr.Message, "does not support tools")
+}
+
+func (o *OllamaProvider) Conversation() []Message {
+	messages := make([]Message, len(o.History))
+	for i, m := range o.History {
+		messages[i] = Message{Role: m.Role, Content: m.Content, Images: m.Images}
+	}
+	return messages
+}
+
+func (o *OllamaProvider) SetConversation(messages []Message) {
+	o.History = make([]ollamaMessage, len(messages))
+	for i, m := range messages {
+		o.History[i] = ollamaMessage{Role: m.Role, Content: m.Content, Images: m.Images}
+	}
+}
+
+func ollamaTokens(messages []ollamaMessage) int {
+	total := 0
+	for _, m := range messages {
+		total += EstimateTokens(m.Content) + len(m.Images)*imageTokens
+		for _, call := range m.ToolCalls {
+			total += EstimateTokens(string(call.Function.Arguments))
+		}
+	}
+	return total
+}
+
+func (o *OllamaProvider) newRequest(ctx context.Context, messages []ollamaMessage, stream bool) *http.Request {
+	if o.System != "" {
+		messages = append([]ollamaMessage{{Role: "system", Content: o.System}}, messages...)
+	}
+
+	var format interface{}
+	if o.format != nil {
+		format = o.format.Schema
+	}
+
+	payload, _ := json.Marshal(ollamaRequest{
+		Model:    o.Model,
+		Format:   format,
+		Messages: messages,
+		Tools:    openAITools(o.activeTools()),
+		Options:  o.requestOptions(),
+		Stream:   stream,
+	})
+
+	req, _ := http.NewRequestWithContext(withTokens(ctx, ollamaTokens(messages)), "POST", o.BaseURL, bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func (o *OllamaProvider) Send(ctx context.Context, prompt string) (string, error) {
+	return o.run(ctx, prompt, nil)
+}
+
+func (o *OllamaProvider) Stream(ctx context.Context, prompt string, onToken func(string)) (string, error) {
+	return o.run(ctx, prompt, onToken)
+}
+
+// run serves both Send and Stream; a nil onToken means a single JSON reply.
+func (o *OllamaProvider) run(ctx context.Context, prompt string, onToken func(string)) (string, error) {
+	images, err := o.takeImages(ctx)
+	if err != nil {
+		return "", err
+	}
+	userMsg := ollamaMessage{Role: "user", Content: prompt, Images: images}
+	currentContext := append(o.History, userMsg)
+	messages := append([]ollamaMessage(nil), currentContext...)
+
+	var turn ollamaMessage
+	ans, err := toolLoop(ctx, o.activeTools(), func() (string, []ToolCall, error) {
+		msg, err := o.chat(ctx, messages, onToken)
+		if isToolsUnsupported(err) && !o.noTools {
+			o.noTools = true
+			msg, err = o.chat(ctx, messages, onToken)
+		}
+		turn = msg
+		if onToken != nil && msg.Content != "" && len(msg.ToolCalls) > 0 {
+			onToken("\n\n")
+		}
+		return msg.Content, ollamaToolCalls(msg.ToolCalls), err
+	}, func(calls []ToolCall, results []string) {
+		messages = appendOllamaToolResults(messages, turn, calls, results)
+	})
+	if err != nil {
+		return "", classifyError("ollama", err)
+	}
+
+	ans = strings.TrimSpace(ans)
+	o.History = append(currentContext, ollamaMessage{Role: "assistant", Content: ans})
+	return ans, nil
+}
+
+func (o *OllamaProvider) chat(ctx context.Context, messages []ollamaMessage, onToken func(string)) (ollamaMessage, error) {
+	turn := ollamaMessage{Role: "assistant"}
+
+	resp, err := o.Retry.Do(o.Client, o.newRequest(ctx, messages, onToken != nil))
+	if err != nil {
+		return turn, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		var res ollamaResponse
+		if json.Unmarshal(body, &res) == nil && res.Error != "" {
+			return turn, &APIError{Provider: "ollama", StatusCode: resp.StatusCode, Message: res.Error}
+		}
+		return turn, newAPIError("ollama", resp.StatusCode, body)
+	}
+
+	if onToken == nil {
+		var res ollamaResponse
+		if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+			return turn, fmt.Errorf("decode error: %v", err)
+		}
+		recordUsage(ctx, "ollama", o.Model, Usage{InputTokens: res.PromptEvalCount, OutputTokens: res.EvalCount})
+		return res.Message, nil
+	}
+
+	var sb strings.Builder
+	var tokens Usage
+	err = readNDJSON(resp.Body, func(line []byte) error {
+		var chunk ollamaResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return fmt.Errorf("decode error: %v", err)
+		}
+		if chunk.Error != "" {
+			return &APIError{Provider: "ollama", Message: chunk.Error}
+		}
+		if chunk.Done {
+			tokens = Usage{InputTokens: chunk.PromptEvalCount, OutputTokens: chunk.EvalCount}
+		}
+		turn.ToolCalls = append(turn.ToolCalls, chunk.Message.ToolCalls...)
+		if chunk.Message.Content != "" {
+			sb.WriteString(chunk.Message.Content)
+			onToken(chunk.Message.Content)
+		}
+		return nil
+	})
+	if err != nil {
+		return turn, err
+	}
+	recordUsage(ctx, "ollama", o.Model, tokens)
+
+	turn.Content = sb.String()
+	return turn, nil
+}
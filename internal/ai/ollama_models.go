@@ -0,0 +1,203 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type OllamaModel struct {
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	Digest     string    `json:"digest"`
+	ModifiedAt time.Time `json:"modified_at"`
+	Details    struct {
+		Family            string `json:"family"`
+		ParameterSize     string `json:"parameter_size"`
+		QuantizationLevel string `json:"quantization_level"`
+	} `json:"details"`
+}
+
+type OllamaModelDetails struct {
+	License      string                 `json:"license"`
+	Modelfile    string                 `json:"modelfile"`
+	Parameters   string                 `json:"parameters"`
+	Template     string                 `json:"template"`
+	Details      map[string]interface{} `json:"details"`
+	ModelInfo    map[string]interface{} `json:"model_info"`
+	Capabilities []string               `json:"capabilities"`
+}
+
+type PullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest"`
+	Total     int64  `json:"total"`
+	Completed int64  `json:"completed"`
+	Error     string `json:"error,omitempty"`
+}
+
+type ModelNotFoundError struct {
+	Provider string
+	Model    string
+}
+
+func (e *ModelNotFoundError) Error() string {
+	return fmt.Sprintf("%s model %q is not installed", e.Provider, e.Model)
+}
+
+type ollamaStatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *ollamaStatusError) Error() string {
+	return fmt.Sprintf("ollama error %d: %s", e.StatusCode, e.Message)
+}
+
+func notFoundAsModelError(err error, name string) error {
+	var se *ollamaStatusError
+	if errors.As(err, &se) && se.StatusCode == http.StatusNotFound {
+		return &ModelNotFoundError{Provider: "ollama", Model: name}
+	}
+	return err
+}
+
+func NewOllamaClient() (*OllamaProvider, error) {
+	if !isOllamaRunning() {
+		return nil, fmt.Errorf("ollama is not running")
+	}
+	return newOllamaProvider(""), nil
+}
+
+func (o *OllamaProvider) apiURL(path string) string {
+	return strings.TrimSuffix(o.BaseURL, "/api/chat") + path
+}
+
+func (o *OllamaProvider) call(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, _ := json.Marshal(body)
+		reader = bytes.NewBuffer(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, o.apiURL(path), reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		msg := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			msg = apiErr.Error
+		}
+		return nil, &ollamaStatusError{StatusCode: resp.StatusCode, Message: msg}
+	}
+
+	return resp, nil
+}
+
+func (o *OllamaProvider) ListModels(ctx context.Context) ([]OllamaModel, error) {
+	resp, err := o.call(ctx, "GET", "/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var res struct {
+		Models []OllamaModel `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("decode error: %v", err)
+	}
+	return res.Models, nil
+}
+
+func (o *OllamaProvider) HasModel(ctx context.Context, name string) (bool, error) {
+	models, err := o.ListModels(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, m := range models {
+		if OllamaModelMatches(m.Name, name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func OllamaModelMatches(installed, wanted string) bool {
+	if installed == wanted {
+		return true
+	}
+	if !strings.Contains(wanted, ":") {
+		return installed == wanted+":latest"
+	}
+	return false
+}
+
+func (o *OllamaProvider) ShowModel(ctx context.Context, name string) (*OllamaModelDetails, error) {
+	resp, err := o.call(ctx, "POST", "/api/show", map[string]string{"model": name})
+	if err != nil {
+		return nil, notFoundAsModelError(err, name)
+	}
+	defer resp.Body.Close()
+
+	var details OllamaModelDetails
+	if err := json.NewDecoder(resp.Body).Decode(&details); err != nil {
+		return nil, fmt.Errorf("decode error: %v", err)
+	}
+	return &details, nil
+}
+
+func (o *OllamaProvider) DeleteModel(ctx context.Context, name string) error {
+	resp, err := o.call(ctx, "DELETE", "/api/delete", map[string]string{"model": name})
+	if err != nil {
+		return notFoundAsModelError(err, name)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (o *OllamaProvider) PullModel(ctx context.Context, name string, onProgress func(PullProgress)) error {
+	// Pulls routinely outlive the chat timeout, so rely on ctx alone here.
+	pullClient := &http.Client{Transport: o.Client.Transport}
+	client := &OllamaProvider{BaseURL: o.BaseURL, Client: pullClient}
+
+	resp, err := client.call(ctx, "POST", "/api/pull", map[string]interface{}{"model": name, "stream": true})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return readNDJSON(resp.Body, func(line []byte) error {
+		var p PullProgress
+		if err := json.Unmarshal(line, &p); err != nil {
+			return fmt.Errorf("decode error: %v", err)
+		}
+		if p.Error != "" {
+			return fmt.Errorf("ollama pull failed: %s", p.Error)
+		}
+		if onProgress != nil {
+			onProgress(p)
+		}
+		return nil
+	})
+}
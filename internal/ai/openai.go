@@ -2,6 +2,7 @@ package ai
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,87 +11,525 @@ import (
 	"time"
 )
 
+const openAIURL = "https://api.openai.com/v1/chat/completions"
+
 type OpenAIProvider struct {
 	ApiKey  string
+	BaseURL string
+	Headers map[string]string
 	Model   string
+	System  string
 	Client  *http.Client
+	Retry   RetryPolicy
 	History []openAIMessage
+
+	compatible bool
+	tools      []Tool
+	pending    []Image
+	options    GenerationOptions
+	format     *Schema
 }
 
 type openAIMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string           `json:"role"`
+	Content    string           `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+	Images     []Image          `json:"-"`
+
+	// Reasoning is what compatible servers such as DeepSeek and vLLM send
+	// as reasoning_content. It is never sent back.
+	Reasoning string `json:"-"`
+}
+
+type openAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+}
+
+type openAIImageURL struct {
+	URL string `json:"url"`
+}
+
+// MarshalJSON switches content to the array form when the message carries
+// images; plain text stays a string for servers that only accept that.
+func (m openAIMessage) MarshalJSON() ([]byte, error) {
+	type plain openAIMessage
+	if len(m.Images) == 0 {
+		return json.Marshal(plain(m))
+	}
+	parts := []openAIContentPart{{Type: "text", Text: m.Content}}
+	for _, img := range m.Images {
+		parts = append(parts, openAIContentPart{Type: "image_url", ImageURL: &openAIImageURL{URL: img.dataURL()}})
+	}
+	return json.Marshal(struct {
+		plain
+		Content []openAIContentPart `json:"content"`
+	}{plain(m), parts})
+}
+
+func (m *openAIMessage) UnmarshalJSON(data []byte) error {
+	type plain openAIMessage
+	var msg struct {
+		plain
+		Reasoning string `json:"reasoning_content"`
+	}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return err
+	}
+	*m = openAIMessage(msg.plain)
+	m.Reasoning = msg.Reasoning
+	return nil
+}
+
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openAITool struct {
+	Type     string             `json:"type"`
+	Function openAIToolFunction `json:"function"`
+}
+
+type openAIToolFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"`
 }
 
 type openAIRequest struct {
-	Model    string          `json:"model"`
-	Messages []openAIMessage `json:"messages"`
+	Model           string                `json:"model"`
+	Messages        []openAIMessage       `json:"messages"`
+	Tools           []openAITool          `json:"tools,omitempty"`
+	Temperature     *float64              `json:"temperature,omitempty"`
+	TopP            *float64              `json:"top_p,omitempty"`
+	MaxTokens       int                   `json:"max_tokens,omitempty"`
+	MaxCompletion   int                   `json:"max_completion_tokens,omitempty"`
+	ReasoningEffort string                `json:"reasoning_effort,omitempty"`
+	Stop            []string              `json:"stop,omitempty"`
+	ResponseFormat  *openAIResponseFormat `json:"response_format,omitempty"`
+	Stream          bool                  `json:"stream,omitempty"`
+	StreamOptions   *openAIStreamOptions  `json:"stream_options,omitempty"`
+}
+
+type openAIResponseFormat struct {
+	Type       string           `json:"type"`
+	JSONSchema openAIJSONSchema `json:"json_schema"`
+}
+
+type openAIJSONSchema struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Schema      map[string]interface{} `json:"schema"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+type openAIError struct {
+	Message string          `json:"message"`
+	Type    string          `json:"type"`
+	Code    json.RawMessage `json:"code,omitempty"`
+}
+
+// Code is a string on OpenAI but a number on some compatible servers.
+func (e *openAIError) apiError(provider string, status int) *APIError {
+	code := strings.Trim(string(e.Code), `"`)
+	if code == "" || code == "null" {
+		code = e.Type
+	}
+	return &APIError{Provider: provider, StatusCode: status, Code: code, Message: e.Message}
 }
 
 type openAIResponse struct {
 	Choices []struct {
-		Message openAIMessage `json:"message"`
+		Message      openAIMessage `json:"message"`
+		FinishReason string        `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage,omitempty"`
+	Error *openAIError `json:"error,omitempty"`
+}
+
+type openAIStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content   string `json:"content"`
+			Reasoning string `json:"reasoning_content"`
+			ToolCalls []struct {
+				Index int `json:"index"`
+				openAIToolCall
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
-	Error *struct {
-		Message string `json:"message"`
-		Type    string `json:"type"`
-	} `json:"error,omitempty"`
+	Usage *openAIUsage `json:"usage,omitempty"`
+	Error *openAIError `json:"error,omitempty"`
 }
 
 func newOpenAIProvider(apiKey, model string) *OpenAIProvider {
 	return &OpenAIProvider{
 		ApiKey:  apiKey,
+		BaseURL: openAIURL,
 		Model:   model,
-		Client:  &http.Client{Timeout: 120 * time.Second},
+		Client:  newHTTPClient("openai", 120*time.Second),
+		Retry:   retryPolicyFromConfig("openai", apiKey),
 		History: []openAIMessage{},
 	}
 }
 
+func newOpenAICompatibleProvider(baseURL, apiKey, model string, headers map[string]string) *OpenAIProvider {
+	p := newOpenAIProvider(apiKey, model)
+	p.BaseURL = chatCompletionsURL(baseURL)
+	p.Client = newHTTPClient("openai-compatible", 120*time.Second)
+	p.Retry = retryPolicyFromConfig("openai-compatible", apiKey)
+	p.Headers = headers
+	p.compatible = true
+	return p
+}
+
+func chatCompletionsURL(baseURL string) string {
+	baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	if strings.HasSuffix(baseURL, "/chat/completions") {
+		return baseURL
+	}
+	return baseURL + "/chat/completions"
+}
+
+func (o *OpenAIProvider) providerType() string {
+	if o.compatible {
+		return "openai-compatible"
+	}
+	return "openai"
+}
+
 func (o *OpenAIProvider) Name() string {
+	if o.compatible {
+		return "OpenAI-compatible (" + o.Model + ")"
+	}
 	return "OpenAI (" + o.Model + ")"
 }
 
+func (o *OpenAIProvider) ModelName() string { return o.Model }
+
 func (o *OpenAIProvider) Reset() {
 	o.History = []openAIMessage{}
 }
 
-func (o *OpenAIProvider) Send(prompt string) (string, error) {
-	url := "https://api.openai.com/v1/chat/completions"
+func (o *OpenAIProvider) SetSystemPrompt(prompt string) {
+	o.System = prompt
+}
 
-	userMsg := openAIMessage{Role: "user", Content: prompt}
-	currentContext := append(o.History, userMsg)
+func (o *OpenAIProvider) SetTools(tools []Tool) {
+	o.tools = tools
+}
+
+func (o *OpenAIProvider) SetOptions(opts GenerationOptions) {
+	o.options = opts
+}
+
+func (o *OpenAIProvider) SetResponseFormat(schema *Schema) {
+	o.format = schema
+}
+
+// Reasoning models only accept the default sampling settings and reject
+// stop sequences, so those options are dropped for them.
+func isOpenAIReasoningModel(model string) bool {
+	for _, prefix := range []string{"o1", "o3", "o4", "gpt-5"} {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
+}
 
-	payload, _ := json.Marshal(openAIRequest{
+func (o *OpenAIProvider) applyOptions(req *openAIRequest) {
+	opts := o.options
+	// OpenAI itself wants max_completion_tokens; compatible servers mostly
+	// still only know max_tokens.
+	if o.compatible {
+		req.MaxTokens = opts.MaxTokens
+	} else {
+		req.MaxCompletion = opts.MaxTokens
+	}
+	if LookupModel(o.Model).Reasoning {
+		req.ReasoningEffort = opts.reasoningEffort()
+	}
+	if !o.compatible && isOpenAIReasoningModel(o.Model) {
+		return
+	}
+	req.Temperature = opts.Temperature
+	req.TopP = opts.TopP
+	req.Stop = opts.Stop
+}
+
+func (o *OpenAIProvider) Attach(images ...Image) {
+	o.pending = append(o.pending, images...)
+}
+
+func (o *OpenAIProvider) takeImages() ([]Image, error) {
+	images := o.pending
+	o.pending = nil
+	return images, checkImages(o.providerType(), o.Model, images)
+}
+
+func openAITools(tools []Tool) []openAITool {
+	var out []openAITool
+	for _, t := range tools {
+		out = append(out, openAITool{
+			Type:     "function",
+			Function: openAIToolFunction{Name: t.Name, Description: t.Description, Parameters: t.Parameters},
+		})
+	}
+	return out
+}
+
+func openAIToolCalls(calls []openAIToolCall) []ToolCall {
+	out := make([]ToolCall, len(calls))
+	for i, c := range calls {
+		out[i] = ToolCall{ID: c.ID, Name: c.Function.Name, Arguments: json.RawMessage(c.Function.Arguments)}
+	}
+	return out
+}
+
+// appendOpenAIToolResults adds the assistant's tool-call turn and one "tool"
+// message per result; Ollama speaks the same shape.
+func appendOpenAIToolResults(messages []openAIMessage, turn openAIMessage, calls []ToolCall, results []string) []openAIMessage {
+	messages = append(messages, turn)
+	for i, call := range calls {
+		messages = append(messages, openAIMessage{Role: "tool", ToolCallID: call.ID, Content: results[i]})
+	}
+	return messages
+}
+
+func (o *OpenAIProvider) Conversation() []Message {
+	messages := make([]Message, len(o.History))
+	for i, m := range o.History {
+		messages[i] = Message{Role: m.Role, Content: m.Content, Images: m.Images}
+	}
+	return messages
+}
+
+func (o *OpenAIProvider) SetConversation(messages []Message) {
+	o.History = make([]openAIMessage, len(messages))
+	for i, m := range messages {
+		o.History[i] = openAIMessage{Role: m.Role, Content: m.Content, Images: m.Images}
+	}
+}
+
+func openAITokens(messages []openAIMessage) int {
+	total := 0
+	for _, m := range messages {
+		total += EstimateTokens(m.Content) + len(m.Images)*imageTokens
+		for _, call := range m.ToolCalls {
+			total += EstimateTokens(call.Function.Arguments)
+		}
+	}
+	return total
+}
+
+func (o *OpenAIProvider) newRequest(ctx context.Context, messages []openAIMessage, stream bool) *http.Request {
+	if o.System != "" {
+		messages = append([]openAIMessage{{Role: "system", Content: o.System}}, messages...)
+	}
+
+	reqBody := openAIRequest{
 		Model:    o.Model,
-		Messages: currentContext,
-	})
+		Messages: messages,
+		Tools:    openAITools(o.tools),
+		Stream:   stream,
+	}
+	if stream {
+		reqBody.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	}
+	o.applyOptions(&reqBody)
+	if o.format != nil {
+		reqBody.ResponseFormat = &openAIResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: openAIJSONSchema{Name: o.format.Name, Description: o.format.Description, Schema: o.format.Schema},
+		}
+	}
+	payload, _ := json.Marshal(reqBody)
 
-	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(payload))
+	req, _ := http.NewRequestWithContext(withTokens(ctx, openAITokens(messages)), "POST", o.BaseURL, bytes.NewBuffer(payload))
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+o.ApiKey)
+	if o.ApiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.ApiKey)
+	}
+	for k, v := range o.Headers {
+		req.Header.Set(k, v)
+	}
+	return req
+}
 
-	resp, err := o.Client.Do(req)
+func (o *OpenAIProvider) Send(ctx context.Context, prompt string) (string, error) {
+	images, err := o.takeImages()
 	if err != nil {
 		return "", err
 	}
+	userMsg := openAIMessage{Role: "user", Content: prompt, Images: images}
+	currentContext := append(o.History, userMsg)
+	messages := append([]openAIMessage(nil), currentContext...)
+
+	var turn openAIMessage
+	ans, err := toolLoop(ctx, o.tools, func() (string, []ToolCall, error) {
+		msg, err := o.complete(ctx, messages)
+		turn = msg
+		return msg.Content, openAIToolCalls(msg.ToolCalls), err
+	}, func(calls []ToolCall, results []string) {
+		messages = appendOpenAIToolResults(messages, turn, calls, results)
+	})
+	if err != nil {
+		return "", classifyError(o.providerType(), err)
+	}
+
+	ans = strings.TrimSpace(ans)
+	o.History = append(currentContext, openAIMessage{Role: "assistant", Content: ans})
+	return ans, nil
+}
+
+func (o *OpenAIProvider) complete(ctx context.Context, messages []openAIMessage) (openAIMessage, error) {
+	resp, err := o.Retry.Do(o.Client, o.newRequest(ctx, messages, false))
+	if err != nil {
+		return openAIMessage{}, err
+	}
 	defer resp.Body.Close()
 
 	body, _ := io.ReadAll(resp.Body)
 
 	var res openAIResponse
 	if err := json.Unmarshal(body, &res); err != nil {
-		return "", fmt.Errorf("parse error: %s", string(body))
+		if resp.StatusCode != http.StatusOK {
+			return openAIMessage{}, newAPIError(o.providerType(), resp.StatusCode, body)
+		}
+		return openAIMessage{}, fmt.Errorf("parse error: %s", string(body))
 	}
 
 	if res.Error != nil {
-		return "", fmt.Errorf("openai error: %s", res.Error.Message)
+		return openAIMessage{}, res.Error.apiError(o.providerType(), resp.StatusCode)
+	}
+
+	if res.Usage != nil {
+		recordUsage(ctx, o.providerType(), o.Model, Usage{InputTokens: res.Usage.PromptTokens, OutputTokens: res.Usage.CompletionTokens})
+	}
+
+	if len(res.Choices) == 0 {
+		return openAIMessage{}, fmt.Errorf("empty response")
+	}
+	if res.Choices[0].FinishReason == "content_filter" {
+		return openAIMessage{}, contentFiltered(o.providerType())
+	}
+	msg := res.Choices[0].Message
+	emitThinking(ctx, msg.Reasoning)
+	return msg, nil
+}
+
+func (o *OpenAIProvider) Stream(ctx context.Context, prompt string, onToken func(string)) (string, error) {
+	images, err := o.takeImages()
+	if err != nil {
+		return "", err
+	}
+	userMsg := openAIMessage{Role: "user", Content: prompt, Images: images}
+	currentContext := append(o.History, userMsg)
+	messages := append([]openAIMessage(nil), currentContext...)
+
+	var turn openAIMessage
+	ans, err := toolLoop(ctx, o.tools, func() (string, []ToolCall, error) {
+		msg, err := o.streamOnce(ctx, messages, onToken)
+		turn = msg
+		if msg.Content != "" && len(msg.ToolCalls) > 0 {
+			onToken("\n\n")
+		}
+		return msg.Content, openAIToolCalls(msg.ToolCalls), err
+	}, func(calls []ToolCall, results []string) {
+		messages = appendOpenAIToolResults(messages, turn, calls, results)
+	})
+	if err != nil {
+		return "", classifyError(o.providerType(), err)
+	}
+
+	ans = strings.TrimSpace(ans)
+	if ans == "" {
+		return "", fmt.Errorf("empty response")
+	}
+	o.History = append(currentContext, openAIMessage{Role: "assistant", Content: ans})
+	return ans, nil
+}
+
+func (o *OpenAIProvider) streamOnce(ctx context.Context, messages []openAIMessage, onToken func(string)) (openAIMessage, error) {
+	turn := openAIMessage{Role: "assistant"}
+
+	resp, err := o.Retry.Do(o.Client, o.newRequest(ctx, messages, true))
+	if err != nil {
+		return turn, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		var res openAIResponse
+		if json.Unmarshal(body, &res) == nil && res.Error != nil {
+			return turn, res.Error.apiError(o.providerType(), resp.StatusCode)
+		}
+		return turn, newAPIError(o.providerType(), resp.StatusCode, body)
 	}
 
-	if len(res.Choices) > 0 {
-		ans := strings.TrimSpace(res.Choices[0].Message.Content)
-		o.History = append(currentContext, openAIMessage{Role: "assistant", Content: ans})
-		return ans, nil
+	var sb strings.Builder
+	var tokens Usage
+	err = readSSE(resp.Body, func(_ string, data []byte) error {
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return fmt.Errorf("parse error: %s", string(data))
+		}
+		if chunk.Error != nil {
+			return chunk.Error.apiError(o.providerType(), 0)
+		}
+		if chunk.Usage != nil {
+			tokens = Usage{InputTokens: chunk.Usage.PromptTokens, OutputTokens: chunk.Usage.CompletionTokens}
+		}
+		if len(chunk.Choices) == 0 {
+			return nil
+		}
+		if chunk.Choices[0].FinishReason == "content_filter" {
+			return contentFiltered(o.providerType())
+		}
+		delta := chunk.Choices[0].Delta
+		emitThinking(ctx, delta.Reasoning)
+		if delta.Content != "" {
+			sb.WriteString(delta.Content)
+			onToken(delta.Content)
+		}
+		// Tool calls arrive in fragments keyed by index: the first carries
+		// the id and name, the rest append to the JSON arguments.
+		for _, d := range delta.ToolCalls {
+			for len(turn.ToolCalls) <= d.Index {
+				turn.ToolCalls = append(turn.ToolCalls, openAIToolCall{Type: "function"})
+			}
+			call := &turn.ToolCalls[d.Index]
+			if d.ID != "" {
+				call.ID = d.ID
+			}
+			call.Function.Name += d.Function.Name
+			call.Function.Arguments += d.Function.Arguments
+		}
+		return nil
+	})
+	if err != nil {
+		return turn, err
 	}
+	recordUsage(ctx, o.providerType(), o.Model, tokens)
 
-	return "", fmt.Errorf("empty response")
+	turn.Content = sb.String()
+	return turn, nil
 }
@@ -0,0 +1,71 @@
+package ai
+
+import "github.com/broman0x/forgeai-cli/internal/config"
+
+// GenerationOptions tune a provider's sampling. Nil or zero fields are left
+// out of the request so the provider's own default applies.
+type GenerationOptions struct {
+	Temperature *float64
+	TopP        *float64
+	MaxTokens   int
+	Stop        []string
+
+	// ReasoningEffort ("low", "medium", "high") and ThinkingBudget (tokens)
+	// only reach models that reason; either one implies the other.
+	ReasoningEffort string
+	ThinkingBudget  int
+}
+
+func Float(v float64) *float64 { return &v }
+
+// Edits want the most likely code, chat can afford to be a little livelier.
+var defaultOptions = map[string]GenerationOptions{
+	"edit": {Temperature: Float(0.2)},
+	"chat": {Temperature: Float(0.7)},
+}
+
+func (o GenerationOptions) IsZero() bool {
+	return o.Temperature == nil && o.TopP == nil && o.MaxTokens == 0 && len(o.Stop) == 0 &&
+		o.ReasoningEffort == "" && o.ThinkingBudget == 0
+}
+
+// Merge returns o with every field that is set in override replaced.
+func (o GenerationOptions) Merge(override GenerationOptions) GenerationOptions {
+	if override.Temperature != nil {
+		o.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		o.TopP = override.TopP
+	}
+	if override.MaxTokens > 0 {
+		o.MaxTokens = override.MaxTokens
+	}
+	if override.Stop != nil {
+		o.Stop = override.Stop
+	}
+	if override.ReasoningEffort != "" || override.ThinkingBudget > 0 {
+		o.ReasoningEffort = override.ReasoningEffort
+		o.ThinkingBudget = override.ThinkingBudget
+	}
+	return o
+}
+
+// OptionsFor layers the built-in defaults for command under the "default"
+// and per-command entries of the config's generation section.
+func OptionsFor(command string) GenerationOptions {
+	opts := defaultOptions[command]
+	gen := config.Load().Generation
+	for _, key := range []string{"default", command} {
+		if c, ok := gen[key]; ok {
+			opts = opts.Merge(GenerationOptions{
+				Temperature:     c.Temperature,
+				TopP:            c.TopP,
+				MaxTokens:       c.MaxTokens,
+				Stop:            c.Stop,
+				ReasoningEffort: c.ReasoningEffort,
+				ThinkingBudget:  c.ThinkingBudget,
+			})
+		}
+	}
+	return opts
+}
@@ -0,0 +1,65 @@
+package ai_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/broman0x/forgeai-cli/internal/ai"
+	"github.com/broman0x/forgeai-cli/internal/ai/aitest"
+	"github.com/broman0x/forgeai-cli/internal/config"
+)
+
+func TestOptionsForLayersConfigOverDefaults(t *testing.T) {
+	home := aitest.Isolate(t)
+	dir := filepath.Join(home, ".config", "forgeai")
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"generation": {
+		"default": {"max_tokens": 2000, "top_p": 0.9},
+		"edit": {"max_tokens": 16000}
+	}}`), 0644)
+	config.ResetCache()
+
+	opts := ai.OptionsFor("edit")
+	if opts.Temperature == nil || *opts.Temperature != 0.2 {
+		t.Fatalf("edit lost its built-in temperature: %+v", opts)
+	}
+	if opts.MaxTokens != 16000 || opts.TopP == nil || *opts.TopP != 0.9 {
+		t.Fatalf("edit options = %+v", opts)
+	}
+	if got := ai.OptionsFor("review"); got.MaxTokens != 2000 || got.Temperature != nil {
+		t.Fatalf("review options = %+v", got)
+	}
+}
+
+func TestClaudeRequestCarriesOptions(t *testing.T) {
+	aitest.Isolate(t)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	capture := &captureTransport{reply: `{"content":[{"type":"text","text":"ok"}]}`}
+	prev := ai.Transport
+	ai.Transport = capture
+	t.Cleanup(func() { ai.Transport = prev })
+
+	prov, err := ai.CreateProvider("claude", "claude-3-5-sonnet-latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := prov.Send(context.Background(), "hi"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(capture.bodies[0], `"max_tokens":8192`) || strings.Contains(capture.bodies[0], "temperature") {
+		t.Fatalf("default request = %s", capture.bodies[0])
+	}
+
+	prov.SetOptions(ai.GenerationOptions{Temperature: ai.Float(0), MaxTokens: 20000, Stop: []string{"END"}})
+	if _, err := prov.Send(context.Background(), "hi"); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"max_tokens":20000`, `"temperature":0`, `"stop_sequences":["END"]`} {
+		if !strings.Contains(capture.bodies[1], want) {
+			t.Fatalf("request %s is missing %s", capture.bodies[1], want)
+		}
+	}
+}
@@ -0,0 +1,430 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/broman0x/forgeai-cli/internal/config"
+)
+
+// Plugins are executables named forgeai-provider-<name>, looked up in the
+// config dir's plugins/ folder and then on PATH. Each call starts the plugin,
+// writes one JSON request line to its stdin and closes it; the plugin answers
+// with JSON lines on stdout and exits:
+//
+//	{"type":"token","text":"..."}                 streamed text, any number
+//	{"type":"thinking","text":"..."}              reasoning, kept out of the answer
+//	{"type":"done","text":"...","usage":{...}}    final answer, optional tool_calls
+//	{"type":"models","models":["a","b"]}          reply to "models"
+//	{"type":"error","error":{"kind":"auth","message":"..."}}
+//
+// Unknown reply types are ignored so plugins can add their own. Stderr is only
+// used in error messages.
+const (
+	PluginPrefix          = "forgeai-provider-"
+	PluginProtocolVersion = 1
+
+	maxPluginLine = 16 << 20
+)
+
+type pluginRequest struct {
+	Version        int             `json:"version"`
+	Method         string          `json:"method"`
+	Model          string          `json:"model,omitempty"`
+	System         string          `json:"system,omitempty"`
+	Messages       []pluginMessage `json:"messages,omitempty"`
+	Tools          []pluginTool    `json:"tools,omitempty"`
+	Options        *pluginOptions  `json:"options,omitempty"`
+	ResponseFormat *pluginFormat   `json:"response_format,omitempty"`
+}
+
+// pluginMessage roles are "user", "assistant" and "tool"; tool results carry
+// the id of the call they answer.
+type pluginMessage struct {
+	Role       string           `json:"role"`
+	Content    string           `json:"content"`
+	Images     []Image          `json:"images,omitempty"`
+	ToolCalls  []pluginToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type pluginTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+type pluginToolCall struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type pluginOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
+	ThinkingBudget  int    `json:"thinking_budget,omitempty"`
+}
+
+type pluginFormat struct {
+	Name   string                 `json:"name"`
+	Schema map[string]interface{} `json:"schema"`
+}
+
+type pluginReply struct {
+	Type      string           `json:"type"`
+	Text      string           `json:"text,omitempty"`
+	ToolCalls []pluginToolCall `json:"tool_calls,omitempty"`
+	Models    []string         `json:"models,omitempty"`
+	Usage     *struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage,omitempty"`
+	Error *pluginError `json:"error,omitempty"`
+}
+
+// pluginError kinds are "auth", "rate_limit", "quota", "context_length",
+// "content_blocked", "timeout" and "unavailable"; anything else is classified
+// from the status and message like an HTTP error.
+type pluginError struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+	Status  int    `json:"status,omitempty"`
+}
+
+func (e *pluginError) typed(provider string) error {
+	apiErr := &APIError{Provider: provider, StatusCode: e.Status, Code: e.Kind, Message: e.Message}
+	switch e.Kind {
+	case "auth":
+		return &AuthError{apiErr}
+	case "rate_limit":
+		return &RateLimitError{apiErr}
+	case "quota":
+		return &QuotaError{apiErr}
+	case "context_length":
+		return &ContextLengthError{apiErr}
+	case "content_blocked":
+		return &ContentBlockedError{apiErr}
+	case "timeout":
+		return &TimeoutError{apiErr}
+	case "unavailable":
+		return &UnavailableError{apiErr}
+	}
+	return classifyError(provider, apiErr)
+}
+
+func pluginDirs() []string {
+	dirs := []string{filepath.Join(config.GetConfigDir(), "plugins")}
+	return append(dirs, filepath.SplitList(os.Getenv("PATH"))...)
+}
+
+func pluginFile(dir, name string) (string, bool) {
+	path := filepath.Join(dir, PluginPrefix+name)
+	candidates := []string{path}
+	if runtime.GOOS == "windows" {
+		candidates = []string{path + ".exe", path + ".bat", path + ".cmd"}
+	}
+	for _, c := range candidates {
+		info, err := os.Stat(c)
+		if err == nil && !info.IsDir() && (runtime.GOOS == "windows" || info.Mode()&0111 != 0) {
+			return c, true
+		}
+	}
+	return "", false
+}
+
+// FindPlugin returns the executable for the plugin called name.
+func FindPlugin(name string) (string, bool) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return "", false
+	}
+	for _, dir := range pluginDirs() {
+		if dir == "" {
+			continue
+		}
+		if path, ok := pluginFile(dir, name); ok {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// ListPlugins returns the names of the installed plugins, sorted.
+func ListPlugins() []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, dir := range pluginDirs() {
+		if dir == "" {
+			continue
+		}
+		entries, _ := os.ReadDir(dir)
+		for _, e := range entries {
+			name := strings.TrimPrefix(e.Name(), PluginPrefix)
+			if name == e.Name() || e.IsDir() {
+				continue
+			}
+			if ext := filepath.Ext(name); runtime.GOOS == "windows" && ext != "" {
+				name = strings.TrimSuffix(name, ext)
+			}
+			if _, ok := pluginFile(dir, name); ok && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+type PluginProvider struct {
+	Plugin  string
+	Path    string
+	Model   string
+	System  string
+	Timeout time.Duration
+	History []pluginMessage
+
+	tools   []Tool
+	pending []Image
+	options GenerationOptions
+	format  *Schema
+}
+
+func newPluginProvider(name, path, model string) *PluginProvider {
+	_, timeout := providerTimeouts(name, defaultConnectTimeout, 120*time.Second)
+	return &PluginProvider{Plugin: name, Path: path, Model: model, Timeout: timeout}
+}
+
+func (p *PluginProvider) Name() string      { return p.Plugin + " (" + p.Model + ")" }
+func (p *PluginProvider) ModelName() string { return p.Model }
+func (p *PluginProvider) Reset()            { p.History = nil }
+
+func (p *PluginProvider) SetSystemPrompt(prompt string)     { p.System = prompt }
+func (p *PluginProvider) SetTools(tools []Tool)             { p.tools = tools }
+func (p *PluginProvider) SetOptions(opts GenerationOptions) { p.options = opts }
+func (p *PluginProvider) SetResponseFormat(schema *Schema)  { p.format = schema }
+func (p *PluginProvider) Attach(images ...Image)            { p.pending = append(p.pending, images...) }
+
+func (p *PluginProvider) Conversation() []Message {
+	var messages []Message
+	for _, m := range p.History {
+		if m.Role == "user" || m.Role == "assistant" {
+			messages = append(messages, Message{Role: m.Role, Content: m.Content, Images: m.Images})
+		}
+	}
+	return messages
+}
+
+func (p *PluginProvider) SetConversation(messages []Message) {
+	p.History = make([]pluginMessage, len(messages))
+	for i, m := range messages {
+		p.History[i] = pluginMessage{Role: m.Role, Content: m.Content, Images: m.Images}
+	}
+}
+
+func (p *PluginProvider) request(method string, messages []pluginMessage) pluginRequest {
+	req := pluginRequest{Version: PluginProtocolVersion, Method: method, Model: p.Model, System: p.System, Messages: messages}
+	for _, t := range p.tools {
+		req.Tools = append(req.Tools, pluginTool{Name: t.Name, Description: t.Description, Parameters: t.Parameters})
+	}
+	if o := p.options; !o.IsZero() {
+		req.Options = &pluginOptions{
+			Temperature:     o.Temperature,
+			TopP:            o.TopP,
+			MaxTokens:       o.MaxTokens,
+			Stop:            o.Stop,
+			ReasoningEffort: o.reasoningEffort(),
+			ThinkingBudget:  o.thinkingBudget(),
+		}
+	}
+	if p.format != nil {
+		req.ResponseFormat = &pluginFormat{Name: p.format.Name, Schema: p.format.Schema}
+	}
+	return req
+}
+
+func (p *PluginProvider) Send(ctx context.Context, prompt string) (string, error) {
+	return p.complete(ctx, prompt, "send", nil)
+}
+
+func (p *PluginProvider) Stream(ctx context.Context, prompt string, onToken func(string)) (string, error) {
+	ans, err := p.complete(ctx, prompt, "stream", onToken)
+	if err == nil && ans == "" {
+		return "", fmt.Errorf("empty response")
+	}
+	return ans, err
+}
+
+func (p *PluginProvider) complete(ctx context.Context, prompt, method string, onToken func(string)) (string, error) {
+	images := p.pending
+	p.pending = nil
+	current := append(p.History, pluginMessage{Role: "user", Content: prompt, Images: images})
+	messages := append([]pluginMessage(nil), current...)
+
+	var calls []pluginToolCall
+	ans, err := toolLoop(ctx, p.tools, func() (string, []ToolCall, error) {
+		reply, err := p.call(ctx, p.request(method, messages), onToken)
+		if err != nil {
+			return "", nil, err
+		}
+		calls = reply.ToolCalls
+		toolCalls := make([]ToolCall, len(calls))
+		for i, c := range calls {
+			toolCalls[i] = ToolCall{ID: c.ID, Name: c.Name, Arguments: c.Arguments}
+		}
+		return reply.Text, toolCalls, nil
+	}, func(_ []ToolCall, results []string) {
+		messages = append(messages, pluginMessage{Role: "assistant", ToolCalls: calls})
+		for i, c := range calls {
+			messages = append(messages, pluginMessage{Role: "tool", Content: results[i], ToolCallID: c.ID})
+		}
+	})
+	if err != nil {
+		return "", classifyError(p.Plugin, err)
+	}
+
+	ans = strings.TrimSpace(ans)
+	p.History = append(current, pluginMessage{Role: "assistant", Content: ans})
+	return ans, nil
+}
+
+func (p *PluginProvider) listModels(ctx context.Context) ([]string, error) {
+	reply, err := p.call(ctx, pluginRequest{Version: PluginProtocolVersion, Method: "models"}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return reply.Models, nil
+}
+
+// call runs the plugin once for req. Tokens go to onToken as they arrive;
+// a "done" reply without text gets the streamed text instead.
+func (p *PluginProvider) call(ctx context.Context, req pluginRequest, onToken func(string)) (pluginReply, error) {
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return pluginReply{}, err
+	}
+	cmd := exec.CommandContext(ctx, p.Path)
+	cmd.Stdin = bytes.NewReader(append(payload, '\n'))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return pluginReply{}, err
+	}
+	if err := cmd.Start(); err != nil {
+		return pluginReply{}, fmt.Errorf("%s error: cannot start plugin: %v", p.Plugin, err)
+	}
+
+	var final *pluginReply
+	var streamed strings.Builder
+	var protoErr error
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxPluginLine)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var reply pluginReply
+		if err := json.Unmarshal(line, &reply); err != nil {
+			protoErr = fmt.Errorf("%s error: invalid reply from plugin: %s", p.Plugin, truncate(string(line), 200))
+			break
+		}
+		switch reply.Type {
+		case "token":
+			streamed.WriteString(reply.Text)
+			if onToken != nil {
+				onToken(reply.Text)
+			}
+		case "thinking":
+			emitThinking(ctx, reply.Text)
+		case "done", "models", "error":
+			final = &reply
+		}
+	}
+	if err := scanner.Err(); err != nil && protoErr == nil {
+		protoErr = fmt.Errorf("%s error: reading plugin output: %v", p.Plugin, err)
+	}
+	if protoErr != nil && cmd.Process != nil {
+		cmd.Process.Kill()
+	}
+	waitErr := cmd.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return pluginReply{}, err
+	}
+	if protoErr != nil {
+		return pluginReply{}, protoErr
+	}
+	if final != nil && final.Type == "error" {
+		if final.Error == nil {
+			final.Error = &pluginError{Message: "unspecified error"}
+		}
+		return pluginReply{}, final.Error.typed(p.Plugin)
+	}
+	if final == nil {
+		detail := strings.TrimSpace(stderr.String())
+		if waitErr != nil {
+			return pluginReply{}, fmt.Errorf("%s error: plugin failed (%v): %s", p.Plugin, waitErr, truncate(detail, 500))
+		}
+		return pluginReply{}, fmt.Errorf("%s error: plugin exited without a reply", p.Plugin)
+	}
+
+	if final.Type == "done" && final.Text == "" {
+		final.Text = streamed.String()
+	}
+	if final.Usage != nil {
+		recordUsage(ctx, p.Plugin, p.Model, Usage{InputTokens: final.Usage.InputTokens, OutputTokens: final.Usage.OutputTokens})
+	}
+	return *final, nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// createPlugin resolves name to an installed plugin. Without a model, the
+// first one the plugin lists is used.
+func createPlugin(name, model string) (Provider, error) {
+	path, ok := FindPlugin(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown provider type: %s", name)
+	}
+	prov := newPluginProvider(name, path, model)
+	if model == "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		models, err := prov.listModels(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(models) == 0 {
+			return nil, fmt.Errorf("%s error: plugin lists no models", name)
+		}
+		prov.Model = models[0]
+	}
+	return prov, nil
+}
@@ -0,0 +1,228 @@
+package ai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/broman0x/forgeai-cli/internal/config"
+)
+
+// The test binary doubles as the plugin: with FORGEAI_TEST_PLUGIN set it
+// speaks the protocol instead of running tests.
+func TestMain(m *testing.M) {
+	if mode := os.Getenv("FORGEAI_TEST_PLUGIN"); mode != "" {
+		runTestPlugin(mode)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func runTestPlugin(mode string) {
+	line, _ := bufio.NewReader(os.Stdin).ReadBytes('\n')
+	if log := os.Getenv("FORGEAI_TEST_PLUGIN_LOG"); log != "" {
+		f, _ := os.OpenFile(log, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		f.Write(line)
+		f.Close()
+	}
+	var req pluginRequest
+	json.Unmarshal(line, &req)
+	out := json.NewEncoder(os.Stdout)
+
+	switch mode {
+	case "error":
+		out.Encode(pluginReply{Type: "error", Error: &pluginError{Kind: "rate_limit", Message: "slow down"}})
+		os.Exit(1)
+	case "crash":
+		fmt.Fprintln(os.Stderr, "boom: gateway unreachable")
+		os.Exit(3)
+	case "garbage":
+		fmt.Println("this is not json")
+		return
+	}
+
+	if req.Method == "models" {
+		out.Encode(pluginReply{Type: "models", Models: []string{"m1", "m2"}})
+		return
+	}
+	last := req.Messages[len(req.Messages)-1]
+	switch {
+	case last.Role == "tool":
+		out.Encode(pluginReply{Type: "done", Text: "tool said " + last.Content})
+	case len(req.Tools) > 0:
+		out.Encode(pluginReply{Type: "done", ToolCalls: []pluginToolCall{{ID: "c1", Name: req.Tools[0].Name, Arguments: json.RawMessage(`{"q":"x"}`)}}})
+	default:
+		out.Encode(pluginReply{Type: "thinking", Text: "greet them"})
+		out.Encode(pluginReply{Type: "token", Text: "Hel"})
+		out.Encode(pluginReply{Type: "token", Text: "lo"})
+		fmt.Fprintln(os.Stdout, `{"type":"progress","percent":50}`)
+		out.Encode(map[string]interface{}{"type": "done", "usage": map[string]int{"input_tokens": 3, "output_tokens": 2}})
+	}
+}
+
+func testPlugin(t *testing.T, mode string) (*PluginProvider, string) {
+	t.Helper()
+	isolateNetwork(t, config.NetworkConfig{})
+	log := filepath.Join(t.TempDir(), "requests.jsonl")
+	t.Setenv("FORGEAI_TEST_PLUGIN", mode)
+	t.Setenv("FORGEAI_TEST_PLUGIN_LOG", log)
+	return newPluginProvider("gateway", os.Args[0], "m1"), log
+}
+
+func pluginRequests(t *testing.T, log string) []pluginRequest {
+	t.Helper()
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reqs []pluginRequest
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var req pluginRequest
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			t.Fatal(err)
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs
+}
+
+func TestPluginStreamsAndKeepsHistory(t *testing.T) {
+	p, log := testPlugin(t, "echo")
+	p.SetSystemPrompt("be brief")
+	p.SetOptions(GenerationOptions{Temperature: Float(0.2), MaxTokens: 100})
+
+	var tokens []string
+	var used Usage
+	var thought string
+	ctx := WithUsageObserver(context.Background(), func(u Usage) { used = u })
+	ctx = WithThinking(ctx, func(text string) { thought += text })
+	ans, err := p.Stream(ctx, "hi", func(tok string) { tokens = append(tokens, tok) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ans != "Hello" || strings.Join(tokens, "|") != "Hel|lo" {
+		t.Fatalf("got %q from tokens %q", ans, tokens)
+	}
+	if used != (Usage{InputTokens: 3, OutputTokens: 2}) {
+		t.Fatalf("usage %+v", used)
+	}
+	if thought != "greet them" {
+		t.Fatalf("thinking %q", thought)
+	}
+
+	if _, err := p.Send(context.Background(), "again"); err != nil {
+		t.Fatal(err)
+	}
+	reqs := pluginRequests(t, log)
+	if len(reqs) != 2 || reqs[0].Method != "stream" || reqs[1].Method != "send" {
+		t.Fatalf("requests %+v", reqs)
+	}
+	first := reqs[0]
+	if first.Version != PluginProtocolVersion || first.Model != "m1" || first.System != "be brief" ||
+		first.Options == nil || *first.Options.Temperature != 0.2 || first.Options.MaxTokens != 100 {
+		t.Fatalf("first request %+v", first)
+	}
+	if got := reqs[1].Messages; len(got) != 3 || got[1].Role != "assistant" || got[1].Content != "Hello" || got[2].Content != "again" {
+		t.Fatalf("second request should carry the history, got %+v", got)
+	}
+}
+
+func TestPluginRunsToolCalls(t *testing.T) {
+	p, log := testPlugin(t, "echo")
+	var args string
+	p.SetTools([]Tool{{Name: "lookup", Parameters: map[string]interface{}{"type": "object"}, Run: func(ctx context.Context, raw json.RawMessage) (string, error) {
+		args = string(raw)
+		return "42", nil
+	}}})
+
+	ans, err := p.Send(context.Background(), "what is it?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ans != "tool said 42" || args != `{"q":"x"}` {
+		t.Fatalf("answer %q, tool args %q", ans, args)
+	}
+	second := pluginRequests(t, log)[1].Messages
+	if len(second) != 3 || len(second[1].ToolCalls) != 1 || second[2].Role != "tool" || second[2].ToolCallID != "c1" {
+		t.Fatalf("tool round messages %+v", second)
+	}
+	if conv := p.Conversation(); len(conv) != 2 || conv[1].Content != "tool said 42" {
+		t.Fatalf("conversation %+v", conv)
+	}
+}
+
+func TestPluginListsModels(t *testing.T) {
+	p, _ := testPlugin(t, "echo")
+	models, err := p.listModels(context.Background())
+	if err != nil || strings.Join(models, ",") != "m1,m2" {
+		t.Fatalf("models %v, err %v", models, err)
+	}
+}
+
+func TestPluginErrors(t *testing.T) {
+	p, _ := testPlugin(t, "error")
+	_, err := p.Send(context.Background(), "hi")
+	var rateLimit *RateLimitError
+	if !errors.As(err, &rateLimit) || !strings.Contains(err.Error(), "gateway error: slow down") {
+		t.Fatalf("error kind should map to RateLimitError, got %v", err)
+	}
+	if len(p.Conversation()) != 0 {
+		t.Fatal("a failed turn should not be kept")
+	}
+
+	p, _ = testPlugin(t, "crash")
+	if _, err := p.Send(context.Background(), "hi"); err == nil || !strings.Contains(err.Error(), "boom: gateway unreachable") {
+		t.Fatalf("crash should report stderr, got %v", err)
+	}
+
+	p, _ = testPlugin(t, "garbage")
+	if _, err := p.Send(context.Background(), "hi"); err == nil || !strings.Contains(err.Error(), "invalid reply") {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestFindPlugin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("relies on the executable bit")
+	}
+	isolateNetwork(t, config.NetworkConfig{})
+	pathDir := t.TempDir()
+	t.Setenv("PATH", pathDir)
+	configDir := filepath.Join(config.GetConfigDir(), "plugins")
+	os.MkdirAll(configDir, 0755)
+
+	install := func(dir, name string, mode os.FileMode) string {
+		path := filepath.Join(dir, PluginPrefix+name)
+		if err := os.WriteFile(path, []byte("#!/bin/sh\n"), mode); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	onPath := install(pathDir, "gateway", 0755)
+	inConfig := install(configDir, "local", 0755)
+	install(pathDir, "local", 0755)
+	install(pathDir, "notexec", 0644)
+
+	if got, ok := FindPlugin("gateway"); !ok || got != onPath {
+		t.Fatalf("gateway: %q %v", got, ok)
+	}
+	if got, ok := FindPlugin("local"); !ok || got != inConfig {
+		t.Fatalf("the config dir should win over PATH, got %q", got)
+	}
+	if _, ok := FindPlugin("notexec"); ok {
+		t.Fatal("files without the executable bit are not plugins")
+	}
+	if got := strings.Join(ListPlugins(), ","); got != "gateway,local" {
+		t.Fatalf("ListPlugins: %s", got)
+	}
+	if _, err := CreateProvider("missing", "x"); err == nil || !strings.Contains(err.Error(), "unknown provider type") {
+		t.Fatalf("got %v", err)
+	}
+}
@@ -1,26 +1,39 @@
 package ai
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
 	"net"
-	"net/http"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/broman0x/forgeai-cli/internal/config"
+	"github.com/broman0x/forgeai-cli/internal/redact"
 	"github.com/spf13/viper"
 )
 
 type Provider interface {
-	Send(prompt string) (string, error)
+	Send(ctx context.Context, prompt string) (string, error)
+	Stream(ctx context.Context, prompt string, onToken func(string)) (string, error)
+	SetSystemPrompt(prompt string)
+	SetTools(tools []Tool)
+	Attach(images ...Image)
+	SetOptions(opts GenerationOptions)
+	SetResponseFormat(schema *Schema)
+	Conversation() []Message
+	SetConversation(messages []Message)
 	Name() string
+	ModelName() string
 	Reset()
 }
 
+type Message struct {
+	Role    string  `json:"role"`
+	Content string  `json:"content"`
+	Images  []Image `json:"images,omitempty"`
+}
+
 func CreateProvider(pType, modelName string) (Provider, error) {
 	pType = strings.ToLower(pType)
 
@@ -45,6 +58,31 @@ func CreateProvider(pType, modelName string) (Provider, error) {
 		}
 		return newOpenAIProvider(key, modelName), nil
 
+	case "openai-compatible", "compatible", "custom":
+		cfg := config.Load().OpenAICompatible
+		if cfg.BaseURL == "" {
+			return nil, fmt.Errorf("openai-compatible base URL not configured")
+		}
+		if modelName == "" {
+			modelName = cfg.Model
+		}
+		if modelName == "" {
+			return nil, fmt.Errorf("openai-compatible model name not configured")
+		}
+		key := os.Getenv("OPENAI_COMPATIBLE_API_KEY")
+		if key == "" {
+			key = cfg.APIKey
+		}
+		// Keys from config.json aren't in the environment, so the redactor
+		// has to be told about them, credential headers included.
+		redact.Register(key)
+		for name, v := range cfg.Headers {
+			if redact.IsSecretName(name) {
+				redact.Register(strings.TrimPrefix(v, "Bearer "))
+			}
+		}
+		return newOpenAICompatibleProvider(cfg.BaseURL, key, modelName, cfg.Headers), nil
+
 	case "claude", "anthropic":
 		key := os.Getenv("ANTHROPIC_API_KEY")
 		if key == "" {
@@ -62,16 +100,27 @@ func CreateProvider(pType, modelName string) (Provider, error) {
 		if modelName == "" {
 			modelName = "llama3"
 		}
-		return newOllamaProvider(modelName), nil
+		prov := newOllamaProvider(modelName)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if ok, err := prov.HasModel(ctx, modelName); err == nil && !ok {
+			return nil, &ModelNotFoundError{Provider: "ollama", Model: modelName}
+		}
+		return prov, nil
 
 	default:
-		return nil, fmt.Errorf("unknown provider type: %s", pType)
+		return createPlugin(pType, modelName)
 	}
 }
 
 func NewProvider() (Provider, error) {
 	cfg := config.Load()
 
+	if chain := newChainFromConfig(cfg.FallbackChain); chain != nil {
+		return chain, nil
+	}
+
 	if cfg.LastProvider != "" && cfg.LastModel != "" {
 		prov, err := CreateProvider(cfg.LastProvider, cfg.LastModel)
 		if err == nil {
@@ -80,7 +129,11 @@ func NewProvider() (Provider, error) {
 	}
 
 	if isOllamaRunning() {
-		return CreateProvider("ollama", "llama3")
+		model := defaultOllamaModel()
+		if model == "" {
+			model = "llama3"
+		}
+		return CreateProvider("ollama", model)
 	}
 
 	configProvider := strings.ToLower(viper.GetString("provider"))
@@ -106,6 +159,41 @@ func NewProvider() (Provider, error) {
 	return nil, fmt.Errorf("no AI provider available. Please set up API key or start Ollama")
 }
 
+func newChainFromConfig(entries []config.ChainEntry) Provider {
+	var providers []Provider
+	for _, e := range entries {
+		prov, err := CreateProvider(e.Provider, e.Model)
+		if err != nil {
+			continue
+		}
+		providers = append(providers, prov)
+	}
+
+	switch len(providers) {
+	case 0:
+		return nil
+	case 1:
+		return providers[0]
+	}
+	return NewChainProvider(providers...)
+}
+
+func defaultOllamaModel() string {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	models, err := newOllamaProvider("").ListModels(ctx)
+	if err != nil || len(models) == 0 {
+		return ""
+	}
+	for _, m := range models {
+		if OllamaModelMatches(m.Name, "llama3") {
+			return "llama3"
+		}
+	}
+	return models[0].Name
+}
+
 func getOllamaHost() string {
 	host := os.Getenv("OLLAMA_HOST")
 	if host == "" {
@@ -125,7 +213,7 @@ func getOllamaPort() string {
 func isOllamaRunning() bool {
 	host := getOllamaHost()
 	port := getOllamaPort()
-	addr := fmt.Sprintf("%s:%s", host, port)
+	addr := net.JoinHostPort(host, port)
 
 	conn, err := net.DialTimeout("tcp", addr, 1000*time.Millisecond)
 	if err != nil {
@@ -134,158 +222,3 @@ func isOllamaRunning() bool {
 	conn.Close()
 	return true
 }
-
-type GeminiProvider struct {
-	ApiKey  string
-	Model   string
-	Client  *http.Client
-	History []geminiContent
-}
-
-type geminiRequest struct {
-	Contents []geminiContent `json:"contents"`
-}
-type geminiContent struct {
-	Role  string       `json:"role,omitempty"`
-	Parts []geminiPart `json:"parts"`
-}
-type geminiPart struct {
-	Text string `json:"text"`
-}
-type geminiResponse struct {
-	Candidates []struct {
-		Content struct {
-			Parts []struct {
-				Text string `json:"text"`
-			} `json:"parts"`
-		} `json:"content"`
-	} `json:"candidates"`
-	Error *struct {
-		Code    int    `json:"code"`
-		Message string `json:"message"`
-	} `json:"error,omitempty"`
-}
-
-func newGeminiProvider(apiKey, model string) *GeminiProvider {
-	return &GeminiProvider{
-		ApiKey:  apiKey,
-		Model:   model,
-		Client:  &http.Client{Timeout: 120 * time.Second},
-		History: []geminiContent{},
-	}
-}
-
-func (g *GeminiProvider) Name() string { return "Gemini (" + g.Model + ")" }
-func (g *GeminiProvider) Reset()       { g.History = []geminiContent{} }
-
-func (g *GeminiProvider) Send(prompt string) (string, error) {
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s", g.Model, g.ApiKey)
-
-	userMsg := geminiContent{Role: "user", Parts: []geminiPart{{Text: prompt}}}
-	currentContext := append(g.History, userMsg)
-
-	payload, _ := json.Marshal(geminiRequest{Contents: currentContext})
-
-	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(payload))
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := g.Client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-
-	var res geminiResponse
-	if err := json.Unmarshal(body, &res); err != nil {
-		return "", fmt.Errorf("parse error: %s", string(body))
-	}
-
-	if res.Error != nil {
-		return "", fmt.Errorf("gemini error (%d): %s", res.Error.Code, res.Error.Message)
-	}
-
-	if len(res.Candidates) > 0 && len(res.Candidates[0].Content.Parts) > 0 {
-		ans := strings.TrimSpace(res.Candidates[0].Content.Parts[0].Text)
-		g.History = append(currentContext, geminiContent{Role: "model", Parts: []geminiPart{{Text: ans}}})
-		return ans, nil
-	}
-
-	return "", fmt.Errorf("empty response")
-}
-
-type OllamaProvider struct {
-	BaseURL string
-	Model   string
-	Client  *http.Client
-	History []ollamaMessage
-}
-
-type ollamaMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
-
-type ollamaRequest struct {
-	Model    string          `json:"model"`
-	Messages []ollamaMessage `json:"messages"`
-	Stream   bool            `json:"stream"`
-}
-
-type ollamaResponse struct {
-	Message ollamaMessage `json:"message"`
-	Done    bool          `json:"done"`
-}
-
-func newOllamaProvider(model string) *OllamaProvider {
-	host := getOllamaHost()
-	port := getOllamaPort()
-	baseURL := fmt.Sprintf("http://%s:%s/api/chat", host, port)
-
-	return &OllamaProvider{
-		BaseURL: baseURL,
-		Model:   model,
-		Client:  &http.Client{Timeout: 300 * time.Second},
-		History: []ollamaMessage{},
-	}
-}
-
-func (o *OllamaProvider) Name() string { return "Ollama (" + o.Model + ")" }
-func (o *OllamaProvider) Reset()       { o.History = []ollamaMessage{} }
-
-func (o *OllamaProvider) Send(prompt string) (string, error) {
-	o.History = append(o.History, ollamaMessage{Role: "user", Content: prompt})
-
-	payload, _ := json.Marshal(ollamaRequest{
-		Model:    o.Model,
-		Messages: o.History,
-		Stream:   false,
-	})
-
-	req, _ := http.NewRequest("POST", o.BaseURL, bytes.NewBuffer(payload))
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := o.Client.Do(req)
-	if err != nil {
-		if strings.Contains(err.Error(), "deadline exceeded") {
-			return "", fmt.Errorf("timeout: model took too long to respond. Try a smaller file or faster model")
-		}
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("ollama error %d: %s", resp.StatusCode, string(body))
-	}
-
-	var res ollamaResponse
-	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
-		return "", fmt.Errorf("decode error: %v", err)
-	}
-
-	ans := strings.TrimSpace(res.Message.Content)
-	o.History = append(o.History, res.Message)
-	return ans, nil
-}
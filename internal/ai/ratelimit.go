@@ -0,0 +1,222 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/broman0x/forgeai-cli/internal/config"
+)
+
+type tokensKey struct{}
+
+// withTokens records the input tokens a request counts against the TPM
+// budget: its text plus imageTokens per image. The body size would not do,
+// as base64 images and tool schemas inflate it many times over.
+func withTokens(ctx context.Context, tokens int) context.Context {
+	return context.WithValue(ctx, tokensKey{}, tokens)
+}
+
+func requestTokens(req *http.Request) int {
+	tokens, _ := req.Context().Value(tokensKey{}).(int)
+	return tokens
+}
+
+func estimateTexts(texts []string) int {
+	total := 0
+	for _, t := range texts {
+		total += EstimateTokens(t)
+	}
+	return total
+}
+
+// RateLimit is a per-minute budget; zero means no limit.
+type RateLimit struct {
+	RequestsPerMinute int
+	TokensPerMinute   int
+}
+
+// rateLimitTiers are conservative figures for the published tiers. The
+// provider's console has the exact numbers for an account, which can be set
+// as rpm/tpm in the "rate_limits" config entry.
+var rateLimitTiers = map[string]map[string]RateLimit{
+	"gemini": {
+		"free":  {RequestsPerMinute: 10, TokensPerMinute: 250000},
+		"tier1": {RequestsPerMinute: 1000, TokensPerMinute: 1000000},
+		"tier2": {RequestsPerMinute: 2000, TokensPerMinute: 3000000},
+	},
+	"openai": {
+		"free":  {RequestsPerMinute: 3, TokensPerMinute: 40000},
+		"tier1": {RequestsPerMinute: 500, TokensPerMinute: 200000},
+		"tier2": {RequestsPerMinute: 5000, TokensPerMinute: 2000000},
+	},
+	"claude": {
+		"tier1": {RequestsPerMinute: 50, TokensPerMinute: 30000},
+		"tier2": {RequestsPerMinute: 1000, TokensPerMinute: 450000},
+	},
+}
+
+// rateLimitFor resolves the config entry for provider: the tier's defaults,
+// then any explicit rpm/tpm on top.
+func rateLimitFor(provider string) RateLimit {
+	entry := config.Load().RateLimits[provider]
+	limit := rateLimitTiers[provider][strings.ToLower(entry.Tier)]
+	if entry.RPM > 0 {
+		limit.RequestsPerMinute = entry.RPM
+	}
+	if entry.TPM > 0 {
+		limit.TokensPerMinute = entry.TPM
+	}
+	return limit
+}
+
+var nowFn = time.Now
+
+// bucket holds up to capacity units and refills capacity units per minute.
+type bucket struct {
+	capacity float64
+	level    float64
+	last     time.Time
+}
+
+func newBucket(perMinute int, now time.Time) *bucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &bucket{capacity: float64(perMinute), level: float64(perMinute), last: now}
+}
+
+func (b *bucket) refill(now time.Time) {
+	b.level += now.Sub(b.last).Minutes() * b.capacity
+	if b.level > b.capacity {
+		b.level = b.capacity
+	}
+	b.last = now
+}
+
+// A request larger than the whole bucket counts as a full one: it waits for
+// the bucket to fill and then empties it, rather than pushing the level so
+// far below zero that the calls after it stall for minutes.
+func (b *bucket) clamp(n float64) float64 {
+	if n > b.capacity {
+		return b.capacity
+	}
+	return n
+}
+
+// wait is how long until n units are available.
+func (b *bucket) wait(n float64) time.Duration {
+	if b == nil {
+		return 0
+	}
+	n = b.clamp(n)
+	if b.level >= n {
+		return 0
+	}
+	return time.Duration((n - b.level) / b.capacity * float64(time.Minute))
+}
+
+func (b *bucket) take(n float64) {
+	if b != nil {
+		b.level -= b.clamp(n)
+	}
+}
+
+// Limiter throttles one provider and key to its requests- and tokens-per-
+// minute budgets.
+type Limiter struct {
+	mu       sync.Mutex
+	requests *bucket
+	tokens   *bucket
+}
+
+func NewLimiter(limit RateLimit) *Limiter {
+	if limit.RequestsPerMinute <= 0 && limit.TokensPerMinute <= 0 {
+		return nil
+	}
+	now := nowFn()
+	return &Limiter{requests: newBucket(limit.RequestsPerMinute, now), tokens: newBucket(limit.TokensPerMinute, now)}
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = map[string]*Limiter{}
+)
+
+// limiterFor shares one limiter between every client for the same provider
+// and key, so concurrent commands draw on the same budget. The key is only
+// kept as a hash.
+func limiterFor(provider, apiKey string) *Limiter {
+	limit := rateLimitFor(provider)
+	if limit.RequestsPerMinute <= 0 && limit.TokensPerMinute <= 0 {
+		return nil
+	}
+
+	sum := sha256.Sum256([]byte(apiKey))
+	id := provider + ":" + hex.EncodeToString(sum[:8])
+
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	if l, ok := limiters[id]; ok {
+		return l
+	}
+	l := NewLimiter(limit)
+	limiters[id] = l
+	return l
+}
+
+type waitKey struct{}
+
+// WithWaitNotifier asks a rate-limited call to report the time left while it
+// waits, so the caller can show it instead of looking stuck.
+func WithWaitNotifier(ctx context.Context, notify func(remaining time.Duration)) context.Context {
+	return context.WithValue(ctx, waitKey{}, notify)
+}
+
+// Wait blocks until one request carrying tokens fits the budget, reporting
+// the remaining wait about once a second. A nil Limiter never waits.
+func (l *Limiter) Wait(ctx context.Context, tokens int) error {
+	if l == nil {
+		return nil
+	}
+	notify, _ := ctx.Value(waitKey{}).(func(time.Duration))
+
+	for {
+		l.mu.Lock()
+		now := nowFn()
+		for _, b := range []*bucket{l.requests, l.tokens} {
+			if b != nil {
+				b.refill(now)
+			}
+		}
+		d := l.requests.wait(1)
+		if t := l.tokens.wait(float64(tokens)); t > d {
+			d = t
+		}
+		if d <= 0 {
+			l.requests.take(1)
+			l.tokens.take(float64(tokens))
+			l.mu.Unlock()
+			if notify != nil {
+				notify(0)
+			}
+			return nil
+		}
+		l.mu.Unlock()
+
+		if notify != nil {
+			notify(d)
+		}
+		step := d
+		if step > time.Second {
+			step = time.Second
+		}
+		if err := sleepFn(ctx, step); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/broman0x/forgeai-cli/internal/config"
+)
+
+// fakeClock makes sleepFn advance nowFn instead of waiting.
+func fakeClock(t *testing.T) *[]time.Duration {
+	t.Helper()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var sleeps []time.Duration
+	origNow, origSleep := nowFn, sleepFn
+	nowFn = func() time.Time { return now }
+	sleepFn = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		now = now.Add(d)
+		return ctx.Err()
+	}
+	t.Cleanup(func() { nowFn, sleepFn = origNow, origSleep })
+	return &sleeps
+}
+
+// waited reports whether sleeps add up to want, give or take the rounding
+// of the per-second steps.
+func waited(sleeps []time.Duration, want time.Duration) bool {
+	var d time.Duration
+	for _, s := range sleeps {
+		d += s
+	}
+	return d >= want-time.Millisecond && d <= want+time.Millisecond
+}
+
+func TestLimiterPacesRequestsPerMinute(t *testing.T) {
+	sleeps := fakeClock(t)
+	l := NewLimiter(RateLimit{RequestsPerMinute: 2})
+
+	for i := 0; i < 2; i++ {
+		if err := l.Wait(context.Background(), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(*sleeps) != 0 {
+		t.Fatalf("burst within the budget should not wait, slept %v", *sleeps)
+	}
+
+	var reported []time.Duration
+	ctx := WithWaitNotifier(context.Background(), func(d time.Duration) { reported = append(reported, d) })
+	if err := l.Wait(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !waited(*sleeps, 30*time.Second) {
+		t.Fatalf("third request slept %v, want 30s in total", *sleeps)
+	}
+	for _, s := range *sleeps {
+		if s > time.Second {
+			t.Fatalf("sleeps should be at most 1s so the countdown updates, got %v", s)
+		}
+	}
+	if len(reported) < 2 || reported[0] != 30*time.Second || reported[len(reported)-1] != 0 {
+		t.Fatalf("unexpected countdown %v", reported)
+	}
+}
+
+func TestLimiterPacesTokensPerMinute(t *testing.T) {
+	sleeps := fakeClock(t)
+	l := NewLimiter(RateLimit{TokensPerMinute: 1000})
+
+	l.Wait(context.Background(), 800)
+	l.Wait(context.Background(), 500)
+	if !waited(*sleeps, 18*time.Second) {
+		t.Fatalf("slept %v for 300 missing tokens, want 18s in total", *sleeps)
+	}
+
+	// More than the whole budget waits for a full bucket rather than forever.
+	*sleeps = nil
+	l.Wait(context.Background(), 5000)
+	if !waited(*sleeps, time.Minute) {
+		t.Fatalf("oversized request slept %v, want 1m in total", *sleeps)
+	}
+}
+
+func TestLimiterOversizedRequestOnlyEmptiesTheBucket(t *testing.T) {
+	sleeps := fakeClock(t)
+	l := NewLimiter(RateLimit{TokensPerMinute: 1000})
+
+	l.Wait(context.Background(), 10000)
+	if len(*sleeps) != 0 {
+		t.Fatalf("a full bucket should let the oversized request through, slept %v", *sleeps)
+	}
+	l.Wait(context.Background(), 500)
+	if !waited(*sleeps, 30*time.Second) {
+		t.Fatalf("next request slept %v, want 30s for half a bucket", *sleeps)
+	}
+}
+
+func TestLimiterWaitStopsOnCancel(t *testing.T) {
+	fakeClock(t)
+	l := NewLimiter(RateLimit{RequestsPerMinute: 1})
+	l.Wait(context.Background(), 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.Wait(ctx, 0); err != context.Canceled {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestNilLimiterNeverWaits(t *testing.T) {
+	var l *Limiter
+	if err := l.Wait(context.Background(), 1<<30); err != nil {
+		t.Fatal(err)
+	}
+	if NewLimiter(RateLimit{}) != nil {
+		t.Fatal("a zero limit should not build a limiter")
+	}
+}
+
+func TestRateLimitForTierAndOverrides(t *testing.T) {
+	isolateNetwork(t, config.NetworkConfig{})
+	config.Save(&config.Config{RateLimits: map[string]config.RateLimitConfig{
+		"gemini": {Tier: "Free", TPM: 1000},
+		"claude": {RPM: 7},
+	}})
+	config.ResetCache()
+
+	if got := rateLimitFor("gemini"); got != (RateLimit{RequestsPerMinute: 10, TokensPerMinute: 1000}) {
+		t.Fatalf("gemini: %+v", got)
+	}
+	if got := rateLimitFor("claude"); got != (RateLimit{RequestsPerMinute: 7}) {
+		t.Fatalf("claude: %+v", got)
+	}
+	if got := rateLimitFor("openai"); got != (RateLimit{}) {
+		t.Fatalf("openai without an entry should be unlimited: %+v", got)
+	}
+
+	if limiterFor("claude", "key-a") != limiterFor("claude", "key-a") {
+		t.Fatal("clients with the same key should share a limiter")
+	}
+	if limiterFor("claude", "key-a") == limiterFor("claude", "key-b") {
+		t.Fatal("different keys should have separate limiters")
+	}
+}
+
+func TestRetryDoWaitsOnLimiter(t *testing.T) {
+	sleeps := fakeClock(t)
+	srv, calls := scriptedServer(t, []scriptedReply{{status: http.StatusOK}}, nil)
+	p := testPolicy()
+	p.Limiter = NewLimiter(RateLimit{RequestsPerMinute: 1})
+
+	for i := 0; i < 2; i++ {
+		resp, err := p.Do(srv.Client(), post(t, context.Background(), srv.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if *calls != 2 || !waited(*sleeps, time.Minute) {
+		t.Fatalf("calls=%d slept %v", *calls, *sleeps)
+	}
+}
+
+func TestRequestTokensCountImagesAsImageTokens(t *testing.T) {
+	isolateNetwork(t, config.NetworkConfig{})
+	screenshot := Image{Name: "shot.png", MIMEType: "image/png", Data: make([]byte, 1<<20)}
+	prompt := "What is wrong with this layout?"
+
+	claude := newClaudeProvider("key", "claude-sonnet-4-20250514")
+	openai := newOpenAIProvider("key", "gpt-4o")
+	gemini := newGeminiProvider("key", "gemini-2.5-flash")
+	ollama := newOllamaProvider("llava")
+	reqs := map[string]*http.Request{
+		"claude": claude.newRequest(context.Background(), []claudeMessage{{Role: "user", Content: prompt, Images: []Image{screenshot}}}, false),
+		"openai": openai.newRequest(context.Background(), []openAIMessage{{Role: "user", Content: prompt, Images: []Image{screenshot}}}, false),
+		"gemini": gemini.newRequest(context.Background(), []geminiContent{geminiUserContent(prompt, []Image{screenshot})}, false),
+		"ollama": ollama.newRequest(context.Background(), []ollamaMessage{{Role: "user", Content: prompt, Images: []Image{screenshot}}}, false),
+	}
+	want := EstimateTokens(prompt) + imageTokens
+	for name, req := range reqs {
+		if got := requestTokens(req); got != want {
+			t.Errorf("%s: %d tokens for a %d-byte body, want %d", name, got, req.ContentLength, want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package ai_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/broman0x/forgeai-cli/internal/ai"
+	"github.com/broman0x/forgeai-cli/internal/ai/aitest"
+	"github.com/broman0x/forgeai-cli/internal/usage"
+)
+
+func replayProvider(t *testing.T, cassette, pType, model, keyEnv string) ai.Provider {
+	t.Helper()
+	aitest.Isolate(t)
+	t.Setenv(keyEnv, "test-key")
+	aitest.UseCassette(t, cassette)
+
+	prov, err := ai.CreateProvider(pType, model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return prov
+}
+
+func streamAll(t *testing.T, prov ai.Provider, prompt string) (string, []string) {
+	t.Helper()
+	var tokens []string
+	ans, err := prov.Stream(context.Background(), prompt, func(tok string) {
+		tokens = append(tokens, tok)
+	})
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	return ans, tokens
+}
+
+func TestOpenAISendFromCassette(t *testing.T) {
+	prov := replayProvider(t, "testdata/openai_send.json", "openai", "gpt-4o-mini", "OPENAI_API_KEY")
+	prov.SetSystemPrompt("Be brief.")
+
+	ans, err := prov.Send(context.Background(), "Say hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ans != "Hello from OpenAI." {
+		t.Fatalf("answer = %q", ans)
+	}
+
+	history := prov.Conversation()
+	if len(history) != 2 || history[1].Role != "assistant" || history[1].Content != ans {
+		t.Fatalf("history = %+v", history)
+	}
+
+	ledger, err := usage.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ledger.Entries) != 1 || ledger.Entries[0].InputTokens != 12 || ledger.Entries[0].OutputTokens != 5 {
+		t.Fatalf("usage entries = %+v", ledger.Entries)
+	}
+}
+
+func TestClaudeStreamFromCassette(t *testing.T) {
+	prov := replayProvider(t, "testdata/claude_stream.json", "claude", "claude-3-5-haiku-20241022", "ANTHROPIC_API_KEY")
+
+	ans, tokens := streamAll(t, prov, "Say hello")
+	if ans != "Hello from Claude." || strings.Join(tokens, "|") != "Hello| from Claude." {
+		t.Fatalf("answer = %q, tokens = %q", ans, tokens)
+	}
+
+	_, err := prov.Stream(context.Background(), "Again", func(string) {})
+	var apiErr *ai.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 401 {
+		t.Fatalf("err = %v, want a 401 APIError", err)
+	}
+	if len(prov.Conversation()) != 2 {
+		t.Fatalf("failed turn leaked into history: %+v", prov.Conversation())
+	}
+}
+
+func TestGeminiStreamFromCassette(t *testing.T) {
+	prov := replayProvider(t, "testdata/gemini_stream.json", "gemini", "gemini-2.5-flash", "GEMINI_API_KEY")
+
+	ans, tokens := streamAll(t, prov, "Say hello")
+	if ans != "Hello from Gemini." || len(tokens) != 2 {
+		t.Fatalf("answer = %q, tokens = %q", ans, tokens)
+	}
+
+	history := prov.Conversation()
+	if len(history) != 2 || history[1].Role != "assistant" {
+		t.Fatalf("gemini roles not normalised: %+v", history)
+	}
+}
@@ -0,0 +1,167 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/broman0x/forgeai-cli/internal/config"
+)
+
+// RetryPolicy retries failed requests with jittered exponential backoff,
+// honoring Retry-After. Its Limiter, if any, paces every attempt to the
+// provider's RPM and TPM budget.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Limiter     *Limiter
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   1 * time.Second,
+	MaxDelay:    30 * time.Second,
+}
+
+var sleepFn = func(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func retryPolicyFromConfig(provider, apiKey string) RetryPolicy {
+	policy := DefaultRetryPolicy
+	if attempts := config.Load().RetryMaxAttempts; attempts > 0 {
+		policy.MaxAttempts = attempts
+	}
+	policy.Limiter = limiterFor(provider, apiKey)
+	return policy
+}
+
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout,
+		529:
+		return true
+	}
+	return false
+}
+
+// isTransientNetError reports transport errors a later attempt, or another
+// backend, can get past: a refused or reset connection, one dropped
+// mid-response, or a timeout. Certificate, DNS, proxy and URL errors fail
+// the same way every time.
+func isTransientNetError(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || isTimeout(err)
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// isRetryableNetError leaves timeouts out: the attempt already used the whole
+// client timeout, and the caller reports a TimeoutError instead.
+func isRetryableNetError(err error) bool {
+	return isTransientNetError(err) && !isTimeout(err)
+}
+
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	shift := attempt - 1
+	if shift > 30 {
+		shift = 30
+	}
+	d := p.BaseDelay << shift
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func (p RetryPolicy) Do(client *http.Client, req *http.Request) (*http.Response, error) {
+	attempts := p.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	ctx := req.Context()
+
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		if err := p.Limiter.Wait(ctx, requestTokens(req)); err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+
+		if attempt >= attempts {
+			return resp, err
+		}
+
+		var delay time.Duration
+		if err != nil {
+			if !isRetryableNetError(err) {
+				return nil, err
+			}
+			delay = p.backoff(attempt)
+		} else {
+			if !isRetryableStatus(resp.StatusCode) {
+				return resp, nil
+			}
+			delay = p.backoff(attempt)
+			if after, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				if after > p.MaxDelay {
+					return resp, nil
+				}
+				delay = after
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if err := sleepFn(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
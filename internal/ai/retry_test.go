@@ -0,0 +1,275 @@
+package ai
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type scriptedReply struct {
+	status     int
+	retryAfter string
+}
+
+func scriptedServer(t *testing.T, replies []scriptedReply, bodies *[]string) (*httptest.Server, *int32) {
+	t.Helper()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&calls, 1)) - 1
+		if bodies != nil {
+			b, _ := io.ReadAll(r.Body)
+			*bodies = append(*bodies, string(b))
+		}
+		reply := replies[len(replies)-1]
+		if n < len(replies) {
+			reply = replies[n]
+		}
+		if reply.retryAfter != "" {
+			w.Header().Set("Retry-After", reply.retryAfter)
+		}
+		w.WriteHeader(reply.status)
+		io.WriteString(w, `{"ok":true}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func recordSleeps(t *testing.T) *[]time.Duration {
+	t.Helper()
+	var sleeps []time.Duration
+	orig := sleepFn
+	sleepFn = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return ctx.Err()
+	}
+	t.Cleanup(func() { sleepFn = orig })
+	return &sleeps
+}
+
+func testPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 4, BaseDelay: 100 * time.Millisecond, MaxDelay: 5 * time.Second}
+}
+
+func post(t *testing.T, ctx context.Context, url string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(`{"prompt":"hi"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestRetryRecoversFromTransientErrors(t *testing.T) {
+	sleeps := recordSleeps(t)
+	var bodies []string
+	srv, calls := scriptedServer(t, []scriptedReply{{status: 503}, {status: 500}, {status: 200}}, &bodies)
+
+	resp, err := testPolicy().Do(srv.Client(), post(t, context.Background(), srv.URL))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if *calls != 3 {
+		t.Fatalf("calls = %d, want 3", *calls)
+	}
+	if len(*sleeps) != 2 {
+		t.Fatalf("sleeps = %v, want 2 backoffs", *sleeps)
+	}
+	for _, b := range bodies {
+		if b != `{"prompt":"hi"}` {
+			t.Fatalf("request body not replayed on retry: %q", b)
+		}
+	}
+}
+
+func TestRetryNeverRetriesAuthErrors(t *testing.T) {
+	for _, status := range []int{401, 403, 400} {
+		sleeps := recordSleeps(t)
+		srv, calls := scriptedServer(t, []scriptedReply{{status: status}, {status: 200}}, nil)
+
+		resp, err := testPolicy().Do(srv.Client(), post(t, context.Background(), srv.URL))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != status || *calls != 1 || len(*sleeps) != 0 {
+			t.Fatalf("status %d: got status=%d calls=%d sleeps=%v", status, resp.StatusCode, *calls, *sleeps)
+		}
+	}
+}
+
+func TestOnlyDroppedConnectionsAndTimeoutsAreTransient(t *testing.T) {
+	dial := func(errno error) error {
+		return &url.Error{Op: "Post", URL: "https://api.example.com", Err: &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", errno)}}
+	}
+	tests := []struct {
+		name             string
+		err              error
+		transient, retry bool
+	}{
+		{"connection refused", dial(syscall.ECONNREFUSED), true, true},
+		{"connection reset", dial(syscall.ECONNRESET), true, true},
+		{"unexpected EOF", &url.Error{Op: "Post", Err: io.ErrUnexpectedEOF}, true, true},
+		{"timeout", &url.Error{Op: "Post", Err: context.DeadlineExceeded}, true, false},
+		{"untrusted certificate", &url.Error{Op: "Post", Err: x509.UnknownAuthorityError{}}, false, false},
+		{"unknown host", &url.Error{Op: "Post", Err: &net.OpError{Op: "dial", Err: &net.DNSError{Name: "api.exmaple.com", IsNotFound: true}}}, false, false},
+		{"bad proxy", &url.Error{Op: "proxyconnect", Err: errors.New("invalid proxy URL")}, false, false},
+		{"unsupported scheme", &url.Error{Op: "Post", Err: fmt.Errorf("unsupported protocol scheme %q", "htps")}, false, false},
+		{"cancelled", context.Canceled, false, false},
+	}
+	for _, tt := range tests {
+		if got := isTransientNetError(tt.err); got != tt.transient {
+			t.Errorf("%s: transient = %v, want %v", tt.name, got, tt.transient)
+		}
+		if got := isRetryableNetError(tt.err); got != tt.retry {
+			t.Errorf("%s: retried = %v, want %v", tt.name, got, tt.retry)
+		}
+		if got := canFailOver(tt.err); got != tt.transient {
+			t.Errorf("%s: fails over = %v, want %v", tt.name, got, tt.transient)
+		}
+	}
+}
+
+func TestRetryRetriesRefusedConnections(t *testing.T) {
+	sleeps := recordSleeps(t)
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	if _, err := testPolicy().Do(srv.Client(), post(t, context.Background(), srv.URL)); err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(*sleeps) != testPolicy().MaxAttempts-1 {
+		t.Fatalf("slept %v, want a backoff before every retry", *sleeps)
+	}
+}
+
+func TestRetryFailsFastOnBadURL(t *testing.T) {
+	sleeps := recordSleeps(t)
+	if _, err := testPolicy().Do(http.DefaultClient, post(t, context.Background(), "htps://api.example.com")); err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(*sleeps) != 0 {
+		t.Fatalf("an unsupported scheme was retried: slept %v", *sleeps)
+	}
+}
+
+func TestRetryHonoursRetryAfter(t *testing.T) {
+	sleeps := recordSleeps(t)
+	srv, calls := scriptedServer(t, []scriptedReply{{status: 429, retryAfter: "2"}, {status: 200}}, nil)
+
+	resp, err := testPolicy().Do(srv.Client(), post(t, context.Background(), srv.URL))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if *calls != 2 {
+		t.Fatalf("calls = %d, want 2", *calls)
+	}
+	if len(*sleeps) != 1 || (*sleeps)[0] != 2*time.Second {
+		t.Fatalf("sleeps = %v, want [2s]", *sleeps)
+	}
+}
+
+func TestRetryGivesUpWhenRetryAfterExceedsMaxDelay(t *testing.T) {
+	sleeps := recordSleeps(t)
+	srv, calls := scriptedServer(t, []scriptedReply{{status: 429, retryAfter: "3600"}, {status: 200}}, nil)
+
+	resp, err := testPolicy().Do(srv.Client(), post(t, context.Background(), srv.URL))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != 429 || *calls != 1 || len(*sleeps) != 0 {
+		t.Fatalf("got status=%d calls=%d sleeps=%v", resp.StatusCode, *calls, *sleeps)
+	}
+}
+
+func TestRetryStopsAfterMaxAttempts(t *testing.T) {
+	recordSleeps(t)
+	srv, calls := scriptedServer(t, []scriptedReply{{status: 503}}, nil)
+
+	resp, err := testPolicy().Do(srv.Client(), post(t, context.Background(), srv.URL))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != 503 || *calls != 4 {
+		t.Fatalf("got status=%d calls=%d, want 503 after 4 calls", resp.StatusCode, *calls)
+	}
+	if string(body) != `{"ok":true}` {
+		t.Fatalf("final response body should be readable, got %q", body)
+	}
+}
+
+func TestRetryStopsWhenContextCancelled(t *testing.T) {
+	srv, calls := scriptedServer(t, []scriptedReply{{status: 503}}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	orig := sleepFn
+	sleepFn = func(ctx context.Context, d time.Duration) error {
+		cancel()
+		return ctx.Err()
+	}
+	t.Cleanup(func() { sleepFn = orig })
+
+	_, err := testPolicy().Do(srv.Client(), post(t, ctx, srv.URL))
+	if err == nil || !strings.Contains(err.Error(), "canceled") {
+		t.Fatalf("err = %v, want context canceled", err)
+	}
+	if *calls != 1 {
+		t.Fatalf("calls = %d, want 1", *calls)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{"Thu, 01 Jan 2026 12:00:30 GMT", 30 * time.Second, true},
+		{"Thu, 01 Jan 2026 11:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestBackoffStaysWithinBounds(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 10, BaseDelay: time.Second, MaxDelay: 8 * time.Second}
+	for attempt := 1; attempt <= 40; attempt++ {
+		d := p.backoff(attempt)
+		if d <= 0 || d > p.MaxDelay {
+			t.Fatalf("backoff(%d) = %v, out of (0, %v]", attempt, d, p.MaxDelay)
+		}
+	}
+}
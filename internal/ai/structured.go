@@ -0,0 +1,175 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Schema asks a provider for JSON matching a JSON schema. Name identifies the
+// schema to the APIs that want one (OpenAI, and the tool Claude is forced to
+// call). Keep schemas to type, properties, required, items, enum and
+// description: that is the subset every provider understands.
+type Schema struct {
+	Name        string
+	Description string
+	Schema      map[string]interface{}
+}
+
+type InvalidJSONError struct {
+	Raw string
+	Err error
+}
+
+func (e *InvalidJSONError) Error() string {
+	return fmt.Sprintf("invalid JSON response: %v", e.Err)
+}
+
+func (e *InvalidJSONError) Unwrap() error { return e.Err }
+
+// SendJSON sends prompt with p switched to structured output and decodes the
+// validated reply into out. A reply that fails validation gets one corrective
+// follow-up before giving up.
+func SendJSON(ctx context.Context, p Provider, prompt string, schema Schema, out interface{}) error {
+	p.SetResponseFormat(&schema)
+	defer p.SetResponseFormat(nil)
+
+	resp, err := p.Send(ctx, prompt)
+	if err != nil {
+		return err
+	}
+	raw, err := decodeJSON(resp, schema, out)
+	if err == nil {
+		return nil
+	}
+
+	retry := fmt.Sprintf("Your reply was not valid: %v. Reply again with only the corrected JSON object, no prose or code fences.", err)
+	resp, err = p.Send(ctx, retry)
+	if err != nil {
+		return err
+	}
+	if raw, err = decodeJSON(resp, schema, out); err != nil {
+		return &InvalidJSONError{Raw: raw, Err: err}
+	}
+	return nil
+}
+
+func decodeJSON(resp string, schema Schema, out interface{}) (string, error) {
+	raw := stripCodeFence(resp)
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return raw, err
+	}
+	if err := validateSchema(schema.Schema, value, "$"); err != nil {
+		return raw, err
+	}
+	return raw, json.Unmarshal([]byte(raw), out)
+}
+
+// Models asked for JSON still sometimes wrap it in a markdown fence.
+func stripCodeFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") {
+		return s
+	}
+	s = strings.TrimPrefix(s, "```")
+	if nl := strings.IndexByte(s, '\n'); nl >= 0 {
+		s = s[nl+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "```"))
+}
+
+func validateSchema(schema map[string]interface{}, value interface{}, path string) error {
+	if schema == nil {
+		return nil
+	}
+
+	if enum := stringList(schema["enum"]); enum != nil {
+		found := false
+		for _, e := range enum {
+			if e == fmt.Sprint(value) {
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s must be one of %s", path, strings.Join(enum, ", "))
+		}
+	}
+
+	typ, _ := schema["type"].(string)
+	switch typ {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an object", path)
+		}
+		for _, name := range stringList(schema["required"]) {
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("%s is missing required field %q", path, name)
+			}
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		names := make([]string, 0, len(props))
+		for name := range props {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			v, ok := obj[name]
+			if !ok {
+				continue
+			}
+			sub, _ := props[name].(map[string]interface{})
+			if err := validateSchema(sub, v, path+"."+name); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an array", path)
+		}
+		items, _ := schema["items"].(map[string]interface{})
+		for i, v := range arr {
+			if err := validateSchema(items, v, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%s must be a string", path)
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%s must be a number", path)
+		}
+	case "integer":
+		if f, ok := value.(float64); !ok || f != float64(int64(f)) {
+			return fmt.Errorf("%s must be an integer", path)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s must be a boolean", path)
+		}
+	}
+	return nil
+}
+
+// stringList reads enum and required lists, which are []string when built
+// in Go and []interface{} when they came from JSON.
+func stringList(v interface{}) []string {
+	switch r := v.(type) {
+	case []string:
+		return r
+	case []interface{}:
+		var out []string
+		for _, n := range r {
+			out = append(out, fmt.Sprint(n))
+		}
+		return out
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package ai_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/broman0x/forgeai-cli/internal/ai"
+	"github.com/broman0x/forgeai-cli/internal/ai/aitest"
+)
+
+var verdictSchema = ai.Schema{
+	Name: "verdict",
+	Schema: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"ok":    map[string]interface{}{"type": "boolean"},
+			"count": map[string]interface{}{"type": "integer"},
+		},
+		"required": []string{"ok", "count"},
+	},
+}
+
+type verdict struct {
+	OK    bool `json:"ok"`
+	Count int  `json:"count"`
+}
+
+func TestClaudeStructuredOutputUsesForcedTool(t *testing.T) {
+	aitest.Isolate(t)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	capture := &captureTransport{reply: `{"content":[{"type":"tool_use","id":"toolu_1","name":"verdict","input":{"ok":true,"count":3}}]}`}
+	prev := ai.Transport
+	ai.Transport = capture
+	t.Cleanup(func() { ai.Transport = prev })
+
+	prov, err := ai.CreateProvider("claude", "claude-3-5-haiku-20241022")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v verdict
+	if err := ai.SendJSON(context.Background(), prov, "judge", verdictSchema, &v); err != nil {
+		t.Fatal(err)
+	}
+	if !v.OK || v.Count != 3 {
+		t.Fatalf("decoded %+v", v)
+	}
+	if !strings.Contains(capture.bodies[0], `"tool_choice":{"type":"tool","name":"verdict"}`) {
+		t.Fatalf("request did not force the schema tool: %s", capture.bodies[0])
+	}
+}
+
+func TestSendJSONGivesUpAfterOneRetry(t *testing.T) {
+	fake := aitest.NewFakeProvider(`{"ok": "yes", "count": 1}`, "```json\n{\"ok\": true}\n```")
+
+	var v verdict
+	err := ai.SendJSON(context.Background(), fake, "judge", verdictSchema, &v)
+	var invalid *ai.InvalidJSONError
+	if !errors.As(err, &invalid) || !strings.Contains(err.Error(), `missing required field "count"`) {
+		t.Fatalf("err = %v", err)
+	}
+	if invalid.Raw != `{"ok": true}` {
+		t.Fatalf("raw = %q, want the fence stripped", invalid.Raw)
+	}
+	if len(fake.Calls()) != 2 {
+		t.Fatalf("calls = %d, want exactly one retry", len(fake.Calls()))
+	}
+}
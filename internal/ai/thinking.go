@@ -0,0 +1,61 @@
+package ai
+
+import "context"
+
+// Claude and Gemini take a thinking budget in tokens, OpenAI an effort
+// level; whichever the options set, the other is read off this table.
+var effortBudgets = map[string]int{
+	"low":    2048,
+	"medium": 8192,
+	"high":   24576,
+}
+
+// Claude refuses budgets below this.
+const minThinkingBudget = 1024
+
+func ValidReasoningEffort(effort string) bool {
+	_, ok := effortBudgets[effort]
+	return ok
+}
+
+func (o GenerationOptions) thinkingBudget() int {
+	budget := o.ThinkingBudget
+	if budget == 0 {
+		budget = effortBudgets[o.ReasoningEffort]
+	}
+	if budget > 0 && budget < minThinkingBudget {
+		budget = minThinkingBudget
+	}
+	return budget
+}
+
+func (o GenerationOptions) reasoningEffort() string {
+	if o.ReasoningEffort != "" || o.ThinkingBudget == 0 {
+		return o.ReasoningEffort
+	}
+	switch {
+	case o.ThinkingBudget < effortBudgets["medium"]:
+		return "low"
+	case o.ThinkingBudget < effortBudgets["high"]:
+		return "medium"
+	}
+	return "high"
+}
+
+type thinkingKey struct{}
+
+// WithThinking has providers pass the model's reasoning to show as it
+// arrives. Thinking never ends up in the answer or the conversation history,
+// and without a handler it is dropped.
+func WithThinking(ctx context.Context, show func(string)) context.Context {
+	return context.WithValue(ctx, thinkingKey{}, show)
+}
+
+func emitThinking(ctx context.Context, text string) {
+	if text == "" {
+		return
+	}
+	if show, ok := ctx.Value(thinkingKey{}).(func(string)); ok {
+		show(text)
+	}
+}
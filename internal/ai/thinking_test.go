@@ -0,0 +1,192 @@
+package ai_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/broman0x/forgeai-cli/internal/ai"
+	"github.com/broman0x/forgeai-cli/internal/ai/aitest"
+	"github.com/broman0x/forgeai-cli/internal/config"
+)
+
+func writeConfig(t *testing.T, home, data string) {
+	t.Helper()
+	dir := filepath.Join(home, ".config", "forgeai")
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, "config.json"), []byte(data), 0644)
+	config.ResetCache()
+}
+
+func useCapture(t *testing.T, capture *captureTransport) {
+	t.Helper()
+	prev := ai.Transport
+	ai.Transport = capture
+	t.Cleanup(func() { ai.Transport = prev })
+}
+
+func sse(events ...string) string {
+	var sb strings.Builder
+	for _, e := range events {
+		sb.WriteString("data: " + e + "\n\n")
+	}
+	return sb.String()
+}
+
+func TestClaudeStreamKeepsThinkingOutOfTheAnswer(t *testing.T) {
+	aitest.Isolate(t)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	capture := &captureTransport{replies: []string{
+		sse(`{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Need the file "}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"first."}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig-1"}}`,
+			`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"read_file","input":{}}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"path\":\"main.go\"}"}}`,
+			`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":30}}`),
+		sse(`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"It is empty."}}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":5}}`),
+	}}
+	useCapture(t, capture)
+
+	prov, err := ai.CreateProvider("claude", "claude-sonnet-4-20250514")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prov.SetOptions(ai.GenerationOptions{Temperature: ai.Float(0.7), MaxTokens: 2000, ReasoningEffort: "medium"})
+	prov.SetTools([]ai.Tool{{Name: "read_file", Parameters: map[string]interface{}{"type": "object"}, Run: func(context.Context, json.RawMessage) (string, error) {
+		return "", nil
+	}}})
+
+	var thoughts []string
+	ctx := ai.WithThinking(context.Background(), func(text string) { thoughts = append(thoughts, text) })
+	var tokens []string
+	ans, err := prov.Stream(ctx, "What is in main.go?", func(tok string) { tokens = append(tokens, tok) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ans != "It is empty." || strings.Join(tokens, "") != "It is empty." {
+		t.Fatalf("answer %q, tokens %q", ans, tokens)
+	}
+	if strings.Join(thoughts, "") != "Need the file first." {
+		t.Fatalf("thinking = %q", thoughts)
+	}
+
+	first := capture.bodies[0]
+	for _, want := range []string{`"thinking":{"type":"enabled","budget_tokens":8192}`, `"max_tokens":10192`} {
+		if !strings.Contains(first, want) {
+			t.Fatalf("request %s is missing %s", first, want)
+		}
+	}
+	if strings.Contains(first, "temperature") {
+		t.Fatalf("temperature must be dropped while thinking: %s", first)
+	}
+	if !strings.Contains(capture.bodies[1], `{"type":"thinking","thinking":"Need the file first.","signature":"sig-1"}`) {
+		t.Fatalf("the thinking block was not echoed with the tool result: %s", capture.bodies[1])
+	}
+	if history := prov.Conversation(); len(history) != 2 || history[1].Content != ans {
+		t.Fatalf("history = %+v", history)
+	}
+}
+
+func TestClaudeSkipsThinkingForStructuredOutput(t *testing.T) {
+	aitest.Isolate(t)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	capture := &captureTransport{reply: `{"content":[{"type":"tool_use","id":"toolu_1","name":"verdict","input":{"ok":true,"count":1}}]}`}
+	useCapture(t, capture)
+
+	prov, _ := ai.CreateProvider("claude", "claude-sonnet-4-20250514")
+	prov.SetOptions(ai.GenerationOptions{ThinkingBudget: 4000})
+	var v verdict
+	if err := ai.SendJSON(context.Background(), prov, "judge", verdictSchema, &v); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(capture.bodies[0], `"thinking"`) {
+		t.Fatalf("forced tool_choice and thinking cannot be combined: %s", capture.bodies[0])
+	}
+}
+
+func TestReasoningReachesOnlyReasoningModels(t *testing.T) {
+	aitest.Isolate(t)
+	t.Setenv("OPENAI_API_KEY", "test-key")
+	t.Setenv("GEMINI_API_KEY", "test-key")
+	tests := []struct {
+		provider, model, reply string
+		want, absent           string
+	}{
+		{"openai", "o3-mini", `{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`, `"reasoning_effort":"medium"`, ""},
+		{"openai", "gpt-4o", `{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`, "", "reasoning_effort"},
+		{"gemini", "gemini-2.5-flash", `{"candidates":[{"content":{"parts":[{"text":"ok"}]}}]}`, `"thinkingConfig":{"thinkingBudget":10000,"includeThoughts":true}`, ""},
+		{"gemini", "gemini-2.0-flash", `{"candidates":[{"content":{"parts":[{"text":"ok"}]}}]}`, "", "thinkingConfig"},
+	}
+	for _, tt := range tests {
+		capture := &captureTransport{reply: tt.reply}
+		useCapture(t, capture)
+		prov, err := ai.CreateProvider(tt.provider, tt.model)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prov.SetOptions(ai.GenerationOptions{ThinkingBudget: 10000})
+		if _, err := prov.Send(context.Background(), "hi"); err != nil {
+			t.Fatalf("%s: %v", tt.model, err)
+		}
+		body := capture.bodies[0]
+		if tt.want != "" && !strings.Contains(body, tt.want) {
+			t.Errorf("%s: request %s is missing %s", tt.model, body, tt.want)
+		}
+		if tt.absent != "" && strings.Contains(body, tt.absent) {
+			t.Errorf("%s: request %s should not carry %s", tt.model, body, tt.absent)
+		}
+	}
+}
+
+func TestThoughtsAreSeparatedFromAnswers(t *testing.T) {
+	home := aitest.Isolate(t)
+	writeConfig(t, home, `{"openai_compatible": {"base_url": "https://api.deepseek.com/v1"}}`)
+	t.Setenv("GEMINI_API_KEY", "test-key")
+	tests := []struct {
+		provider, model, reply string
+	}{
+		{"gemini", "gemini-2.5-pro", `{"candidates":[{"content":{"parts":[{"text":"Compare both.","thought":true},{"text":"Use a map."}]}}],
+			"usageMetadata":{"promptTokenCount":4,"candidatesTokenCount":3,"thoughtsTokenCount":20}}`},
+		{"openai-compatible", "deepseek-reasoner", `{"choices":[{"message":{"role":"assistant","content":"Use a map.","reasoning_content":"Compare both."}}]}`},
+	}
+	for _, tt := range tests {
+		useCapture(t, &captureTransport{reply: tt.reply})
+		prov, err := ai.CreateProvider(tt.provider, tt.model)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var thought string
+		var used ai.Usage
+		ctx := ai.WithThinking(context.Background(), func(text string) { thought += text })
+		ctx = ai.WithUsageObserver(ctx, func(u ai.Usage) { used = u })
+		ans, err := prov.Send(ctx, "slice or map?")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.provider, err)
+		}
+		if ans != "Use a map." || thought != "Compare both." {
+			t.Errorf("%s: answer %q, thinking %q", tt.provider, ans, thought)
+		}
+		if tt.provider == "gemini" && used.OutputTokens != 23 {
+			t.Errorf("thinking tokens should be billed as output, got %+v", used)
+		}
+	}
+}
+
+func TestReasoningOptionsFromConfig(t *testing.T) {
+	home := aitest.Isolate(t)
+	writeConfig(t, home, `{"generation": {"default": {"reasoning_effort": "low"}, "review": {"thinking_budget": 16000}}}`)
+
+	if got := ai.OptionsFor("chat"); got.ReasoningEffort != "low" {
+		t.Fatalf("chat options = %+v", got)
+	}
+	if got := ai.OptionsFor("review"); got.ThinkingBudget != 16000 || got.ReasoningEffort != "" {
+		t.Fatalf("a per-command budget should replace the default effort, got %+v", got)
+	}
+}
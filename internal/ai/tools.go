@@ -0,0 +1,71 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// maxToolRounds caps how many times a single prompt may go back to the model
+// with tool results before we give up on getting a final answer.
+const maxToolRounds = 8
+
+// Tool is a function the model may call while answering. Parameters is a JSON
+// schema object describing the arguments; Run receives them as raw JSON and
+// its result is handed back to the model as text.
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  map[string]interface{}
+	Run         func(ctx context.Context, args json.RawMessage) (string, error)
+}
+
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments json.RawMessage
+}
+
+func runTool(ctx context.Context, tools []Tool, call ToolCall) string {
+	args := call.Arguments
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+	for _, t := range tools {
+		if t.Name != call.Name {
+			continue
+		}
+		out, err := t.Run(ctx, args)
+		if err != nil {
+			// The model gets the error as the result so it can correct itself.
+			return "error: " + err.Error()
+		}
+		return out
+	}
+	return fmt.Sprintf("error: unknown tool %q", call.Name)
+}
+
+// toolLoop drives one prompt through as many tool rounds as the model needs.
+// step sends the current messages and returns the reply text plus any tool
+// calls; reply appends the model's turn and the tool results so the next step
+// sees them.
+func toolLoop(ctx context.Context, tools []Tool, step func() (string, []ToolCall, error), reply func(calls []ToolCall, results []string)) (string, error) {
+	for round := 0; ; round++ {
+		text, calls, err := step()
+		if err != nil || len(calls) == 0 {
+			return text, err
+		}
+		if round == maxToolRounds {
+			return "", fmt.Errorf("tool error: model still calling tools after %d rounds", maxToolRounds)
+		}
+
+		results := make([]string, len(calls))
+		for i, call := range calls {
+			if err := ctx.Err(); err != nil {
+				return "", err
+			}
+			results[i] = runTool(ctx, tools, call)
+		}
+		reply(calls, results)
+	}
+}
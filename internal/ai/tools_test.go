@@ -0,0 +1,46 @@
+package ai_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/broman0x/forgeai-cli/internal/ai"
+)
+
+func TestClaudeStreamRunsTools(t *testing.T) {
+	prov := replayProvider(t, "testdata/claude_tools.json", "claude", "claude-3-5-haiku-20241022", "ANTHROPIC_API_KEY")
+
+	var gotPath string
+	prov.SetTools([]ai.Tool{{
+		Name:       "read_file",
+		Parameters: map[string]interface{}{"type": "object"},
+		Run: func(_ context.Context, args json.RawMessage) (string, error) {
+			var a struct {
+				Path string `json:"path"`
+			}
+			if err := json.Unmarshal(args, &a); err != nil {
+				return "", err
+			}
+			gotPath = a.Path
+			return "package main", nil
+		},
+	}})
+
+	ans, tokens := streamAll(t, prov, "What is in main.go?")
+	if gotPath != "main.go" {
+		t.Fatalf("tool got path %q, want the streamed arguments reassembled", gotPath)
+	}
+	if ans != "main.go is empty." {
+		t.Fatalf("answer = %q", ans)
+	}
+	if !strings.HasPrefix(strings.Join(tokens, ""), "Let me look.\n\nmain.go") {
+		t.Fatalf("tokens = %q", tokens)
+	}
+
+	history := prov.Conversation()
+	if len(history) != 2 || history[1].Content != ans {
+		t.Fatalf("tool turns leaked into history: %+v", history)
+	}
+}
@@ -0,0 +1,198 @@
+package ai
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/broman0x/forgeai-cli/internal/cassette"
+	"github.com/broman0x/forgeai-cli/internal/config"
+)
+
+// Transport overrides the round tripper used by every provider client.
+// Tests point it at a cassette; nil means the default network transport.
+var Transport http.RoundTripper
+
+var (
+	envTransportOnce sync.Once
+	envTransport     http.RoundTripper
+)
+
+// FORGEAI_CASSETTE lets demos run against a recorded cassette, or record a
+// new one with FORGEAI_CASSETTE_MODE=record.
+func transportFromEnv() http.RoundTripper {
+	envTransportOnce.Do(func() {
+		path := os.Getenv("FORGEAI_CASSETTE")
+		if path == "" {
+			return
+		}
+		mode := cassette.Mode(os.Getenv("FORGEAI_CASSETTE_MODE"))
+		if mode == "" {
+			mode = cassette.Replay
+		}
+		rec, err := cassette.New(path, mode)
+		if err != nil {
+			envTransport = failingTransport{err}
+			return
+		}
+		envTransport = rec
+	})
+	return envTransport
+}
+
+type failingTransport struct{ err error }
+
+func (f failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, f.err
+}
+
+const defaultConnectTimeout = 30 * time.Second
+
+// newHTTPClient builds the client for one provider. The response timeout
+// defaults to the given value; both timeouts can be overridden per provider
+// in config. Tests and cassettes replace the transport wholesale.
+func newHTTPClient(provider string, timeout time.Duration) *http.Client {
+	connect, response := providerTimeouts(provider, defaultConnectTimeout, timeout)
+
+	rt := Transport
+	if rt == nil {
+		rt = transportFromEnv()
+	}
+	if rt == nil {
+		rt = newTransport(config.Load().Network.CAFile, connect)
+	}
+	return &http.Client{Timeout: response, Transport: rt}
+}
+
+func providerTimeouts(provider string, connect, response time.Duration) (time.Duration, time.Duration) {
+	timeouts := config.Load().Network.Timeouts
+	for _, key := range []string{"default", provider} {
+		t := timeouts[key]
+		if t.ConnectSeconds > 0 {
+			connect = time.Duration(t.ConnectSeconds) * time.Second
+		}
+		if t.ResponseSeconds > 0 {
+			response = time.Duration(t.ResponseSeconds) * time.Second
+		}
+	}
+	return connect, response
+}
+
+// newTransport is http.DefaultTransport with our proxy lookup, an optional
+// extra CA bundle and the configured connect timeout. A CA file that can't
+// be used fails every request rather than silently falling back.
+func newTransport(caFile string, connect time.Duration) http.RoundTripper {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = proxyFromEnv()
+	t.DialContext = (&net.Dialer{Timeout: connect, KeepAlive: 30 * time.Second}).DialContext
+	t.TLSHandshakeTimeout = connect
+
+	if caFile != "" {
+		pool, err := certPool(caFile)
+		if err != nil {
+			return failingTransport{err}
+		}
+		t.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	return t
+}
+
+// certPool adds the PEM certificates in path to the system roots.
+func certPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ca file error: %v", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("ca file error: no PEM certificates in %s", path)
+	}
+	return pool, nil
+}
+
+// proxyFromEnv follows the HTTPS_PROXY/HTTP_PROXY/NO_PROXY conventions of
+// http.ProxyFromEnvironment, but reads the environment when the client is
+// built rather than once per process, so a proxy set in .env applies.
+func proxyFromEnv() func(*http.Request) (*url.URL, error) {
+	httpsProxy := getenvAny("HTTPS_PROXY", "https_proxy")
+	httpProxy := getenvAny("HTTP_PROXY", "http_proxy")
+	noProxy := getenvAny("NO_PROXY", "no_proxy")
+
+	return func(req *http.Request) (*url.URL, error) {
+		proxy := httpProxy
+		if req.URL.Scheme == "https" {
+			proxy = httpsProxy
+		}
+		if proxy == "" || bypassProxy(req.URL, noProxy) {
+			return nil, nil
+		}
+		u, err := url.Parse(proxy)
+		if err != nil || u.Host == "" {
+			// Like the standard library, accept a bare host:port.
+			if u, err = url.Parse("http://" + proxy); err != nil {
+				return nil, fmt.Errorf("invalid proxy address %q: %v", proxy, err)
+			}
+		}
+		return u, nil
+	}
+}
+
+func getenvAny(names ...string) string {
+	for _, n := range names {
+		if v := os.Getenv(n); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// bypassProxy reports whether u's host is loopback or matches a NO_PROXY
+// entry: "*", a host or domain (with or without a leading dot), an optional
+// port, or a CIDR block.
+func bypassProxy(u *url.URL, noProxy string) bool {
+	host, port := u.Hostname(), u.Port()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	if ip != nil && ip.IsLoopback() {
+		return true
+	}
+
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+			continue
+		case entry == "*":
+			return true
+		}
+		if _, block, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && block.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			if p != port {
+				continue
+			}
+			entry = h
+		}
+		entry = strings.TrimPrefix(entry, ".")
+		if host == entry || strings.HasSuffix(host, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,179 @@
+package ai
+
+import (
+	"encoding/pem"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/broman0x/forgeai-cli/internal/config"
+)
+
+func isolateNetwork(t *testing.T, network config.NetworkConfig) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("APPDATA", "")
+	for _, name := range []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy", "NO_PROXY", "no_proxy"} {
+		t.Setenv(name, "")
+	}
+	config.ResetCache()
+	t.Cleanup(config.ResetCache)
+	if err := config.Save(&config.Config{Network: network}); err != nil {
+		t.Fatal(err)
+	}
+	config.ResetCache()
+}
+
+func tlsServer(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	t.Cleanup(srv.Close)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	block := &pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(block), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return srv, caFile
+}
+
+func get(client *http.Client, target string) (string, error) {
+	resp, err := client.Get(target)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
+
+func TestHTTPClientTrustsConfiguredCA(t *testing.T) {
+	srv, caFile := tlsServer(t)
+
+	isolateNetwork(t, config.NetworkConfig{})
+	if _, err := get(newHTTPClient("openai", time.Minute), srv.URL); err == nil {
+		t.Fatal("expected a certificate error without the CA file")
+	}
+
+	isolateNetwork(t, config.NetworkConfig{CAFile: caFile})
+	body, err := get(newHTTPClient("openai", time.Minute), srv.URL)
+	if err != nil || body != "ok" {
+		t.Fatalf("got %q, %v", body, err)
+	}
+}
+
+func TestHTTPClientRejectsUnusableCAFile(t *testing.T) {
+	bad := filepath.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(bad, []byte("not a certificate"), 0644)
+	isolateNetwork(t, config.NetworkConfig{CAFile: bad})
+
+	if _, err := get(newHTTPClient("openai", time.Minute), "https://example.com"); err == nil {
+		t.Fatal("expected an error for a CA file without certificates")
+	}
+}
+
+// connectProxy tunnels CONNECT requests to target, whatever host was asked for.
+func connectProxy(t *testing.T, target string) (*httptest.Server, *int32) {
+	t.Helper()
+	var tunnels int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "CONNECT only", http.StatusMethodNotAllowed)
+			return
+		}
+		atomic.AddInt32(&tunnels, 1)
+		upstream, err := net.Dial("tcp", target)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		if buf.Reader.Buffered() > 0 {
+			io.CopyN(upstream, buf, int64(buf.Reader.Buffered()))
+		}
+		go func() {
+			io.Copy(upstream, conn)
+			upstream.Close()
+		}()
+		io.Copy(conn, upstream)
+		conn.Close()
+	}))
+	t.Cleanup(proxy.Close)
+	return proxy, &tunnels
+}
+
+func TestHTTPClientUsesHTTPSProxy(t *testing.T) {
+	srv, caFile := tlsServer(t)
+	proxy, tunnels := connectProxy(t, srv.Listener.Addr().String())
+
+	isolateNetwork(t, config.NetworkConfig{CAFile: caFile})
+	t.Setenv("HTTPS_PROXY", proxy.URL)
+
+	// The test certificate is issued for example.com; only the proxy knows
+	// where that host really is.
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	body, err := get(newHTTPClient("claude", time.Minute), "https://example.com:"+port+"/v1/messages")
+	if err != nil || body != "ok" {
+		t.Fatalf("got %q, %v", body, err)
+	}
+	if atomic.LoadInt32(tunnels) != 1 {
+		t.Fatalf("proxy saw %d tunnels, want 1", *tunnels)
+	}
+}
+
+func TestNoProxy(t *testing.T) {
+	tests := []struct {
+		target, noProxy string
+		bypass          bool
+	}{
+		{"https://api.openai.com/v1", "", false},
+		{"http://localhost:11434/api/chat", "", true},
+		{"http://127.0.0.1:11434/api/chat", "", true},
+		{"https://api.openai.com/v1", "*", true},
+		{"https://api.openai.com/v1", "openai.com", true},
+		{"https://api.openai.com/v1", ".openai.com", true},
+		{"https://notopenai.com/v1", "openai.com", false},
+		{"https://llm.corp:8443/v1", "llm.corp:8443", true},
+		{"https://llm.corp:9443/v1", "llm.corp:8443", false},
+		{"https://10.1.2.3/v1", "10.0.0.0/8", true},
+		{"https://api.anthropic.com/v1", "openai.com, googleapis.com", false},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.target)
+		if got := bypassProxy(u, tt.noProxy); got != tt.bypass {
+			t.Errorf("bypassProxy(%s, %q) = %v, want %v", tt.target, tt.noProxy, got, tt.bypass)
+		}
+	}
+}
+
+func TestProviderTimeoutsFromConfig(t *testing.T) {
+	isolateNetwork(t, config.NetworkConfig{Timeouts: map[string]config.TimeoutConfig{
+		"default": {ConnectSeconds: 5},
+		"ollama":  {ResponseSeconds: 900},
+	}})
+
+	connect, response := providerTimeouts("ollama", defaultConnectTimeout, 300*time.Second)
+	if connect != 5*time.Second || response != 900*time.Second {
+		t.Fatalf("ollama timeouts = %v, %v", connect, response)
+	}
+	connect, response = providerTimeouts("gemini", defaultConnectTimeout, 120*time.Second)
+	if connect != 5*time.Second || response != 120*time.Second {
+		t.Fatalf("gemini timeouts = %v, %v", connect, response)
+	}
+}
@@ -0,0 +1,55 @@
+package ai
+
+import (
+	"context"
+
+	"github.com/broman0x/forgeai-cli/internal/usage"
+)
+
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
+}
+
+type Price struct {
+	Input  float64
+	Output float64
+}
+
+func PriceFor(model string) (Price, bool) {
+	if price := LookupModel(model).Price; price != nil {
+		return *price, true
+	}
+	return Price{}, false
+}
+
+func (u Usage) Cost(model string) float64 {
+	price, ok := PriceFor(model)
+	if !ok {
+		return 0
+	}
+	return (float64(u.InputTokens)*price.Input + float64(u.OutputTokens)*price.Output) / 1_000_000
+}
+
+type usageObserverKey struct{}
+
+// WithUsageObserver has every request made with ctx report its token usage
+// to observe, on top of the usage ledger.
+func WithUsageObserver(ctx context.Context, observe func(Usage)) context.Context {
+	return context.WithValue(ctx, usageObserverKey{}, observe)
+}
+
+func recordUsage(ctx context.Context, provider, model string, u Usage) {
+	if u.InputTokens == 0 && u.OutputTokens == 0 {
+		return
+	}
+	if observe, ok := ctx.Value(usageObserverKey{}).(func(Usage)); ok {
+		observe(u)
+	}
+
+	cost := u.Cost(model)
+	if provider == "ollama" {
+		cost = 0
+	}
+	usage.Add(ctx, provider, model, u.InputTokens, u.OutputTokens, cost)
+}
@@ -0,0 +1,31 @@
+package ai_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/broman0x/forgeai-cli/internal/ai"
+	"github.com/broman0x/forgeai-cli/internal/ai/aitest"
+)
+
+func TestUsageCost(t *testing.T) {
+	home := aitest.Isolate(t)
+	writeConfig(t, home, `{"models": {"my-finetune": {"input_price": 1, "output_price": 4}}}`)
+
+	tests := []struct {
+		model string
+		usage ai.Usage
+		want  float64
+	}{
+		{"gpt-4o", ai.Usage{InputTokens: 1_000_000, OutputTokens: 100_000}, 2.50 + 1.00},
+		{"claude-3-5-haiku-20241022", ai.Usage{InputTokens: 2000, OutputTokens: 500}, 0.0016 + 0.002},
+		{"my-finetune-v2", ai.Usage{InputTokens: 500_000, OutputTokens: 250_000}, 0.5 + 1},
+		{"llama3.2", ai.Usage{InputTokens: 5000, OutputTokens: 5000}, 0},
+		{"some-new-model", ai.Usage{InputTokens: 5000}, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.usage.Cost(tt.model); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: cost %v, want %v", tt.model, got, tt.want)
+		}
+	}
+}
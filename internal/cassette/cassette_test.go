@@ -0,0 +1,97 @@
+package cassette
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordThenReplayWithoutNetwork(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"echo":`+string(body)+`}`)
+	}))
+
+	path := filepath.Join(t.TempDir(), "echo.json")
+	rec, err := New(path, Record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: rec}
+
+	for _, body := range []string{`"one"`, `"two"`} {
+		resp, err := client.Post(srv.URL+"/v1/chat?key=secret&alt=sse", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(got) != `{"echo":`+body+`}` {
+			t.Fatalf("record mode changed the live response: %s", got)
+		}
+	}
+	srv.Close()
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "secret") {
+		t.Fatalf("cassette leaked the API key:\n%s", data)
+	}
+
+	replay, err := New(path, Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = &http.Client{Transport: replay}
+
+	for _, want := range []string{`{"echo":"one"}`, `{"echo":"two"}`} {
+		resp, err := client.Post(srv.URL+"/v1/chat?alt=sse&key=other", "application/json", strings.NewReader(`{}`))
+		if err != nil {
+			t.Fatalf("replay failed: %v", err)
+		}
+		got, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(got) != want {
+			t.Fatalf("replayed %s, want %s", got, want)
+		}
+		if resp.Header.Get("Content-Type") != "application/json" {
+			t.Fatalf("content type not replayed: %q", resp.Header.Get("Content-Type"))
+		}
+	}
+	if replay.Remaining() != 0 {
+		t.Fatalf("remaining = %d, want 0", replay.Remaining())
+	}
+}
+
+func TestReplayFailsOnUnknownRequest(t *testing.T) {
+	path := filepath.Join("testdata", "status.json")
+	rec, err := New(path, Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: rec}
+
+	resp, err := client.Get("https://api.example.com/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", resp.StatusCode)
+	}
+
+	_, err = client.Get("https://api.example.com/status")
+	if err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Fatalf("err = %v, want missing interaction error", err)
+	}
+}
+
+func TestNewRejectsMissingCassette(t *testing.T) {
+	if _, err := New(filepath.Join(t.TempDir(), "missing.json"), Replay); err == nil {
+		t.Fatal("expected an error for a missing cassette")
+	}
+}
@@ -15,6 +15,108 @@ type Config struct {
 	LastProvider string `json:"last_provider"`
 	InstallPath  string `json:"install_path"`
 	Version      string `json:"version"`
+
+	RetryMaxAttempts int `json:"retry_max_attempts,omitempty"`
+
+	OpenAICompatible OpenAICompatibleConfig `json:"openai_compatible,omitempty"`
+
+	FallbackChain []ChainEntry `json:"fallback_chain,omitempty"`
+
+	ResponseCache CacheConfig `json:"response_cache,omitempty"`
+
+	HistoryBudgetTokens int `json:"history_budget_tokens,omitempty"`
+
+	Generation map[string]GenerationConfig `json:"generation,omitempty"`
+
+	Network NetworkConfig `json:"network,omitempty"`
+
+	Embedding EmbeddingConfig `json:"embedding,omitempty"`
+
+	RateLimits map[string]RateLimitConfig `json:"rate_limits,omitempty"`
+
+	Models map[string]ModelConfig `json:"models,omitempty"`
+
+	// ThinkingDisplay is how chat shows a model's reasoning: "collapsed"
+	// (the default, a summary line to expand with /thinking), "full" or
+	// "hidden".
+	ThinkingDisplay string `json:"thinking_display,omitempty"`
+}
+
+// ModelConfig adds a model to the capability registry or corrects a built-in
+// entry. Keys are model-name prefixes; unset fields keep the built-in values.
+// Prices are USD per million tokens.
+type ModelConfig struct {
+	ContextWindow int      `json:"context_window,omitempty"`
+	MaxOutput     int      `json:"max_output,omitempty"`
+	Vision        *bool    `json:"vision,omitempty"`
+	Tools         *bool    `json:"tools,omitempty"`
+	Reasoning     *bool    `json:"reasoning,omitempty"`
+	InputPrice    *float64 `json:"input_price,omitempty"`
+	OutputPrice   *float64 `json:"output_price,omitempty"`
+}
+
+// RateLimitConfig is keyed by provider. Tier picks the built-in limits for
+// that account tier ("free", "tier1", "tier2"); RPM and TPM override them.
+// Without an entry requests are not throttled on this side.
+type RateLimitConfig struct {
+	Tier string `json:"tier,omitempty"`
+	RPM  int    `json:"rpm,omitempty"`
+	TPM  int    `json:"tpm,omitempty"`
+}
+
+// EmbeddingConfig picks the provider and model "forge index" and
+// "ask --repo" embed with; empty fields fall back to the chat provider's
+// default embedding model.
+type EmbeddingConfig struct {
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+}
+
+// NetworkConfig applies to every provider's HTTP client. Proxies come from
+// HTTPS_PROXY, HTTP_PROXY and NO_PROXY. Timeouts are keyed by provider
+// ("gemini", "openai", "openai-compatible", "claude", "ollama") or "default".
+type NetworkConfig struct {
+	CAFile   string                   `json:"ca_file,omitempty"`
+	Timeouts map[string]TimeoutConfig `json:"timeouts,omitempty"`
+}
+
+// ConnectSeconds bounds dialing and the TLS handshake; ResponseSeconds bounds
+// the whole request, streamed body included.
+type TimeoutConfig struct {
+	ConnectSeconds  int `json:"connect_seconds,omitempty"`
+	ResponseSeconds int `json:"response_seconds,omitempty"`
+}
+
+// GenerationConfig is keyed by command name ("edit", "review", "chat", "ask")
+// or "default", which the command entries fall back to field by field.
+type GenerationConfig struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+
+	// ReasoningEffort is "low", "medium" or "high"; ThinkingBudget is in
+	// tokens. Set one, the other is derived for providers that want it.
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
+	ThinkingBudget  int    `json:"thinking_budget,omitempty"`
+}
+
+type CacheConfig struct {
+	Disabled  bool `json:"disabled,omitempty"`
+	TTLHours  int  `json:"ttl_hours,omitempty"`
+	MaxSizeMB int  `json:"max_size_mb,omitempty"`
+}
+
+type ChainEntry struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model,omitempty"`
+}
+
+type OpenAICompatibleConfig struct {
+	BaseURL string            `json:"base_url,omitempty"`
+	APIKey  string            `json:"api_key,omitempty"`
+	Model   string            `json:"model,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
 }
 
 var globalConfig *Config
@@ -27,6 +129,10 @@ func GetConfigPath() string {
 	return filepath.Join(home, ".config", "forgeai", "config.json")
 }
 
+func GetConfigDir() string {
+	return filepath.Dir(GetConfigPath())
+}
+
 func Load() *Config {
 	if globalConfig != nil {
 		return globalConfig
@@ -89,6 +195,16 @@ func SaveLastModel(provider, model string) error {
 	return Save(cfg)
 }
 
+func SaveOpenAICompatible(baseURL, model string, headers map[string]string) error {
+	cfg := Load()
+	cfg.OpenAICompatible.BaseURL = baseURL
+	cfg.OpenAICompatible.Model = model
+	if headers != nil {
+		cfg.OpenAICompatible.Headers = headers
+	}
+	return Save(cfg)
+}
+
 func SaveLanguage(language string) error {
 	cfg := Load()
 	cfg.Language = language
@@ -0,0 +1,367 @@
+// Package index keeps a local semantic index of a repository: source files
+// are split into line-range chunks, embedded, and stored under .forgeai/ so
+// questions can be answered from the most similar chunks.
+package index
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/broman0x/forgeai-cli/internal/ai"
+	"github.com/broman0x/forgeai-cli/internal/config"
+)
+
+const (
+	Dir      = ".forgeai"
+	fileName = "index.json"
+	version  = 1
+
+	chunkLines   = 60
+	chunkOverlap = 10
+	// Keeps each chunk well inside every embedding model's input limit.
+	maxChunkBytes = 6000
+	batchSize     = 32
+	maxFileBytes  = 1 << 20
+)
+
+var ErrNoIndex = errors.New("no index found. Run 'forge index' first")
+
+type Chunk struct {
+	File      string    `json:"file"`
+	StartLine int       `json:"start_line"`
+	EndLine   int       `json:"end_line"`
+	Text      string    `json:"text"`
+	Vector    []float32 `json:"vector"`
+}
+
+type fileEntry struct {
+	Hash   string  `json:"hash"`
+	Chunks []Chunk `json:"chunks"`
+}
+
+// Index is keyed by slash-separated paths relative to its root. Embedder,
+// Provider and Model record what built it; vectors from another model don't
+// compare.
+type Index struct {
+	Version  int                   `json:"version"`
+	Embedder string                `json:"embedder"`
+	Provider string                `json:"provider,omitempty"`
+	Model    string                `json:"model"`
+	Files    map[string]*fileEntry `json:"files"`
+
+	root string
+}
+
+type Stats struct {
+	Files     int
+	Chunks    int
+	Updated   int
+	Unchanged int
+	Removed   int
+}
+
+type Result struct {
+	Chunk
+	Score float64
+}
+
+func Path(root string) string {
+	return filepath.Join(root, Dir, fileName)
+}
+
+func New(root string) *Index {
+	return &Index{Version: version, Files: map[string]*fileEntry{}, root: root}
+}
+
+func Load(root string) (*Index, error) {
+	data, err := os.ReadFile(Path(root))
+	if os.IsNotExist(err) {
+		return nil, ErrNoIndex
+	}
+	if err != nil {
+		return nil, fmt.Errorf("index error: %v", err)
+	}
+
+	idx := New(root)
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, fmt.Errorf("index error: %s: %v", Path(root), err)
+	}
+	if idx.Version != version {
+		return nil, ErrNoIndex
+	}
+	if idx.Files == nil {
+		idx.Files = map[string]*fileEntry{}
+	}
+	return idx, nil
+}
+
+// Save writes the index atomically and keeps .forgeai/ out of git.
+func (idx *Index) Save() error {
+	dir := filepath.Join(idx.root, Dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	ignore := filepath.Join(dir, ".gitignore")
+	if _, err := os.Stat(ignore); os.IsNotExist(err) {
+		os.WriteFile(ignore, []byte("*\n"), 0644)
+	}
+
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	tmp := Path(idx.root) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, Path(idx.root))
+}
+
+func (idx *Index) Len() int {
+	n := 0
+	for _, f := range idx.Files {
+		n += len(f.Chunks)
+	}
+	return n
+}
+
+// OpenEmbedder builds the embedder the index was made with, so switching
+// chat providers doesn't invalidate it. An "embedding" config entry still
+// wins; Update then rebuilds the index with it. Indexes without a recorded
+// provider, and new ones, get ai.NewEmbedder.
+func (idx *Index) OpenEmbedder() (ai.Embedder, error) {
+	if idx.Provider == "" || config.Load().Embedding.Provider != "" {
+		return ai.NewEmbedder()
+	}
+	return ai.CreateEmbedder(idx.Provider, idx.Model)
+}
+
+// compatible reports whether vectors from emb can be compared with the ones
+// already stored.
+func (idx *Index) compatible(emb ai.Embedder) bool {
+	return idx.Embedder == emb.Name() && idx.Model == emb.ModelName()
+}
+
+// Update re-embeds files whose content changed since the last run, drops
+// files that are gone, and saves. progress gets bytes embedded so far and the
+// total to embed. Files finished before an error are kept.
+func (idx *Index) Update(ctx context.Context, emb ai.Embedder, files []string, progress func(done, total int64)) (Stats, error) {
+	var stats Stats
+	if !idx.compatible(emb) {
+		idx.Files = map[string]*fileEntry{}
+		idx.Embedder, idx.Model = emb.Name(), emb.ModelName()
+	}
+	idx.Provider = ai.EmbedderProvider(emb)
+
+	type pendingFile struct {
+		rel   string
+		entry *fileEntry
+	}
+	var pending []pendingFile
+	var total int64
+	seen := map[string]bool{}
+
+	for _, path := range files {
+		rel, err := filepath.Rel(idx.root, path)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		info, err := os.Stat(path)
+		if err != nil || info.Size() > maxFileBytes {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		seen[rel] = true
+
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:])
+		if old, ok := idx.Files[rel]; ok && old.Hash == hash {
+			stats.Unchanged++
+			continue
+		}
+
+		entry := &fileEntry{Hash: hash, Chunks: split(rel, string(data))}
+		for _, c := range entry.Chunks {
+			total += int64(len(c.Text))
+		}
+		pending = append(pending, pendingFile{rel, entry})
+	}
+
+	for rel := range idx.Files {
+		if !seen[rel] {
+			delete(idx.Files, rel)
+			stats.Removed++
+		}
+	}
+
+	// Batches cross file boundaries; a file is committed to the index once
+	// all of its chunks have vectors.
+	next := 0
+	commit := func() {
+		for next < len(pending) && embedded(pending[next].entry) {
+			idx.Files[pending[next].rel] = pending[next].entry
+			stats.Updated++
+			next++
+		}
+	}
+
+	var batch []*Chunk
+	var done int64
+	flush := func() error {
+		if len(batch) > 0 {
+			texts := make([]string, len(batch))
+			for i, c := range batch {
+				texts[i] = embedText(c)
+			}
+			vectors, err := emb.Embed(ctx, texts)
+			if err != nil {
+				return err
+			}
+			for i, c := range batch {
+				c.Vector = vectors[i]
+				done += int64(len(c.Text))
+			}
+			batch = batch[:0]
+			if progress != nil {
+				progress(done, total)
+			}
+		}
+		commit()
+		return nil
+	}
+
+	var err error
+	for _, p := range pending {
+		for i := range p.entry.Chunks {
+			batch = append(batch, &p.entry.Chunks[i])
+			if len(batch) == batchSize {
+				if err = flush(); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = flush()
+	}
+
+	stats.Files = len(idx.Files)
+	stats.Chunks = idx.Len()
+	if saveErr := idx.Save(); err == nil {
+		err = saveErr
+	}
+	return stats, err
+}
+
+func embedded(entry *fileEntry) bool {
+	for _, c := range entry.Chunks {
+		if c.Vector == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// The path helps the model match questions about a file by name.
+func embedText(c *Chunk) string {
+	return fmt.Sprintf("File: %s (lines %d-%d)\n%s", c.File, c.StartLine, c.EndLine, c.Text)
+}
+
+// split cuts content into overlapping windows of lines. Blank windows are
+// skipped and long ones are trimmed to maxChunkBytes by trim.
+func split(file, content string) []Chunk {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	var chunks []Chunk
+	for start := 0; start < len(lines); start += chunkLines - chunkOverlap {
+		end := start + chunkLines
+		if end > len(lines) {
+			end = len(lines)
+		}
+		text := trim(strings.Join(lines[start:end], "\n"))
+		if strings.TrimSpace(text) != "" {
+			last := start + strings.Count(text, "\n") + 1
+			chunks = append(chunks, Chunk{File: file, StartLine: start + 1, EndLine: last, Text: text})
+		}
+		if end == len(lines) {
+			break
+		}
+	}
+	return chunks
+}
+
+// trim cuts text to at most maxChunkBytes, at the last line break that fits,
+// or on a rune boundary when a single line is longer than that.
+func trim(text string) string {
+	if len(text) <= maxChunkBytes {
+		return text
+	}
+	if i := strings.LastIndexByte(text[:maxChunkBytes+1], '\n'); i > 0 {
+		return text[:i]
+	}
+	cut := maxChunkBytes
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut]
+}
+
+// Search returns the k chunks most similar to query, best first.
+func (idx *Index) Search(ctx context.Context, emb ai.Embedder, query string, k int) ([]Result, error) {
+	if idx.Len() == 0 {
+		return nil, ErrNoIndex
+	}
+	if !idx.compatible(emb) {
+		return nil, fmt.Errorf("index was built with %s; run 'forge index' again to use %s", idx.Embedder, emb.Name())
+	}
+
+	vectors, err := emb.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	q := vectors[0]
+
+	var results []Result
+	for _, f := range idx.Files {
+		for _, c := range f.Chunks {
+			results = append(results, Result{Chunk: c, Score: cosine(q, c.Vector)})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results, nil
+}
+
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
@@ -0,0 +1,203 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/broman0x/forgeai-cli/internal/ai"
+	"github.com/broman0x/forgeai-cli/internal/ai/aitest"
+	"github.com/broman0x/forgeai-cli/internal/config"
+)
+
+// wordEmbedder hashes words into a small bag-of-words vector, enough for
+// texts sharing words to score as similar.
+type wordEmbedder struct {
+	model string
+	texts int
+}
+
+func (w *wordEmbedder) Name() string      { return "Fake (" + w.model + ")" }
+func (w *wordEmbedder) ModelName() string { return w.model }
+
+func (w *wordEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	w.texts += len(texts)
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, 256)
+		words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) })
+		for _, word := range words {
+			h := fnv.New32a()
+			h.Write([]byte(word))
+			v[h.Sum32()%256]++
+		}
+		vectors[i] = v
+	}
+	return vectors, nil
+}
+
+func writeFiles(t *testing.T, root string, files map[string]string) []string {
+	t.Helper()
+	var paths []string
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func TestUpdateOnlyReembedsChangedFiles(t *testing.T) {
+	root := t.TempDir()
+	paths := writeFiles(t, root, map[string]string{
+		"keys.go":  "func validateAPIKey(key string) error { return checkKey(key) }",
+		"spin.go":  "func spinner() { draw frames on the terminal }",
+		"other.go": "package other",
+	})
+	emb := &wordEmbedder{model: "m1"}
+
+	stats, err := New(root).Update(context.Background(), emb, paths, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Updated != 3 || emb.texts != 3 {
+		t.Fatalf("first run: %+v, embedded %d texts", stats, emb.texts)
+	}
+
+	os.WriteFile(filepath.Join(root, "spin.go"), []byte("func spinner() { draw faster }"), 0644)
+	os.Remove(filepath.Join(root, "other.go"))
+	paths = []string{filepath.Join(root, "keys.go"), filepath.Join(root, "spin.go")}
+
+	idx, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	emb.texts = 0
+	stats, err = idx.Update(context.Background(), emb, paths, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Updated != 1 || stats.Unchanged != 1 || stats.Removed != 1 || emb.texts != 1 {
+		t.Fatalf("second run: %+v, embedded %d texts", stats, emb.texts)
+	}
+
+	// A different model invalidates every stored vector.
+	other := &wordEmbedder{model: "m2"}
+	stats, err = idx.Update(context.Background(), other, paths, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Updated != 2 || other.texts != 2 {
+		t.Fatalf("model change: %+v, embedded %d texts", stats, other.texts)
+	}
+}
+
+func TestOpenEmbedderKeepsTheIndexProvider(t *testing.T) {
+	aitest.Isolate(t)
+	t.Setenv("GEMINI_API_KEY", "test-key")
+	t.Setenv("OPENAI_API_KEY", "test-key")
+	root := t.TempDir()
+
+	built, err := ai.CreateEmbedder("gemini", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(root).Update(context.Background(), built, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Switching the chat provider must not change the embedder.
+	config.Save(&config.Config{LastProvider: "openai"})
+	config.ResetCache()
+	idx, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	emb, err := idx.OpenEmbedder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idx.Provider != "gemini" || emb.Name() != built.Name() || !idx.compatible(emb) {
+		t.Fatalf("index by %s (%s) opened %s", idx.Embedder, idx.Provider, emb.Name())
+	}
+
+	// An explicit embedding entry still wins.
+	config.Save(&config.Config{LastProvider: "openai", Embedding: config.EmbeddingConfig{Provider: "openai"}})
+	config.ResetCache()
+	if emb, err = idx.OpenEmbedder(); err != nil || ai.EmbedderProvider(emb) != "openai" {
+		t.Fatalf("configured embedder ignored: %v, %v", emb, err)
+	}
+}
+
+func TestSearchRanksMatchingChunkFirst(t *testing.T) {
+	root := t.TempDir()
+	paths := writeFiles(t, root, map[string]string{
+		"auth/keys.go": "func validateAPIKey(key string) error { return checkKey(key) }",
+		"ui/spin.go":   "func spinner() { draw frames on the terminal }",
+	})
+	emb := &wordEmbedder{model: "m1"}
+	idx := New(root)
+	if _, err := idx.Update(context.Background(), emb, paths, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := idx.Search(context.Background(), emb, "where do we validateAPIKey key", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].File != "auth/keys.go" {
+		t.Fatalf("got %+v", results)
+	}
+
+	if _, err := idx.Search(context.Background(), &wordEmbedder{model: "m2"}, "key", 1); err == nil {
+		t.Fatal("expected an error searching with a different model")
+	}
+}
+
+func TestLoadWithoutIndex(t *testing.T) {
+	if _, err := Load(t.TempDir()); !errors.Is(err, ErrNoIndex) {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestSplitOverlapsAndNumbersLines(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 130; i++ {
+		lines = append(lines, "line")
+	}
+	chunks := split("a.go", strings.Join(lines, "\n"))
+	if len(chunks) != 3 {
+		t.Fatalf("got %d chunks", len(chunks))
+	}
+	if chunks[0].StartLine != 1 || chunks[0].EndLine != 60 || chunks[1].StartLine != 51 || chunks[2].EndLine != 130 {
+		t.Fatalf("unexpected ranges: %+v", chunks)
+	}
+}
+
+func TestSplitTrimsLongChunksOnLineAndRuneBoundaries(t *testing.T) {
+	// Only fourteen of these 400-byte lines fit in maxChunkBytes.
+	line := strings.Repeat("é", 200)
+	var lines []string
+	for i := 0; i < 20; i++ {
+		lines = append(lines, line)
+	}
+	chunks := split("a.txt", strings.Join(lines, "\n"))
+	if c := chunks[0]; len(c.Text) > maxChunkBytes || c.EndLine != 14 || strings.Count(c.Text, "\n") != 13 {
+		t.Fatalf("got %d bytes ending at line %d", len(c.Text), c.EndLine)
+	}
+
+	// One line longer than the limit is cut between runes.
+	chunks = split("b.txt", "x"+strings.Repeat("é", maxChunkBytes))
+	if c := chunks[0]; len(c.Text) > maxChunkBytes || !utf8.ValidString(c.Text) || c.EndLine != 1 {
+		t.Fatalf("got %d bytes, valid UTF-8 %v, ending at line %d", len(c.Text), utf8.ValidString(c.Text), c.EndLine)
+	}
+}
@@ -32,4 +32,21 @@ var EN = map[string]string{
 	"uninstall":       "Uninstall",
 	"uninstall_desc":  "Remove ForgeAI from system",
 	"exit_desc":       "Close application",
+
+	"err_auth":            "The API key was rejected. Check it, or set a new one from the menu (Change API Key).",
+	"err_rate_limit":      "Rate limit reached. Wait a moment and try again, or switch to another provider.",
+	"err_quota":           "Your quota or credit is used up. Check billing on the provider's dashboard.",
+	"err_context_length":  "The input is too long for this model. Use a smaller file, start a new chat, or pick a model with a larger context window.",
+	"err_content_blocked": "The provider's safety filter blocked this request. Rephrase it and try again.",
+	"err_timeout":         "The model took too long to respond. Try a smaller file or a faster model.",
+	"err_unavailable":     "The provider is unreachable or overloaded. Check your connection (and that Ollama is running) or try again later.",
+	"rate_limit_wait":     "rate limited, waiting %ds",
+
+	"prompt_near_limit":     "This prompt is about %d tokens, close to the model's %d-token limit; the answer may be cut short.",
+	"prompt_unknown_window": "This prompt is about %d tokens and the context window of %s is unknown, so it may be truncated. Add the model under \"models\" in config.json.",
+
+	"thinking_label":   "Thinking",
+	"thinking_words":   "%d words",
+	"thinking_summary": "Thought for %ds (%d words)",
+	"thinking_none":    "The last reply came without any thinking.",
 }
@@ -32,4 +32,21 @@ var ID = map[string]string{
 	"uninstall":       "Uninstall",
 	"uninstall_desc":  "Hapus ForgeAI dari sistem",
 	"exit_desc":       "Tutup aplikasi",
+
+	"err_auth":            "API key ditolak. Periksa kembali, atau atur key baru dari menu (Change API Key).",
+	"err_rate_limit":      "Batas rate tercapai. Tunggu sebentar lalu coba lagi, atau ganti ke provider lain.",
+	"err_quota":           "Kuota atau kredit kamu sudah habis. Periksa billing di dashboard provider.",
+	"err_context_length":  "Input terlalu panjang untuk model ini. Gunakan file yang lebih kecil, mulai chat baru, atau pilih model dengan context window lebih besar.",
+	"err_content_blocked": "Filter keamanan provider memblokir permintaan ini. Ubah kalimatnya lalu coba lagi.",
+	"err_timeout":         "Model terlalu lama merespons. Coba file yang lebih kecil atau model yang lebih cepat.",
+	"err_unavailable":     "Provider tidak bisa dihubungi atau sedang sibuk. Periksa koneksi (dan pastikan Ollama berjalan) atau coba lagi nanti.",
+	"rate_limit_wait":     "kena batas rate, menunggu %d detik",
+
+	"prompt_near_limit":     "Prompt ini sekitar %d token, mendekati batas model %d token; jawabannya bisa terpotong.",
+	"prompt_unknown_window": "Prompt ini sekitar %d token dan ukuran context window %s tidak diketahui, jadi bisa terpotong. Tambahkan model di \"models\" pada config.json.",
+
+	"thinking_label":   "Berpikir",
+	"thinking_words":   "%d kata",
+	"thinking_summary": "Berpikir selama %d detik (%d kata)",
+	"thinking_none":    "Balasan terakhir tidak menyertakan proses berpikir.",
 }
@@ -0,0 +1,117 @@
+// Package redact scrubs API keys and tokens from text before ForgeAI prints
+// or saves it.
+package redact
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const mask = "[REDACTED]"
+
+// Values shorter than this are too likely to appear in ordinary text.
+const minSecretLen = 8
+
+var (
+	mu      sync.RWMutex
+	secrets = map[string]bool{}
+)
+
+// Environment variables whose values are always treated as secrets.
+var envSuffixes = []string{"_API_KEY", "_TOKEN", "_SECRET"}
+
+// IsSecretName reports whether a header or variable name looks like it
+// holds a credential.
+func IsSecretName(name string) bool {
+	name = strings.ToLower(name)
+	for _, hint := range []string{"key", "auth", "token", "secret", "password"} {
+		if strings.Contains(name, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// Token shapes worth scrubbing even when the value was never registered.
+var patterns = []*regexp.Regexp{
+	regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_\-]{20,}`),
+	regexp.MustCompile(`\bsk-(?:proj-|svcacct-)?[A-Za-z0-9_\-]{20,}`),
+	regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}`),
+	regexp.MustCompile(`gh[pousr]_[A-Za-z0-9]{36,}`),
+	regexp.MustCompile(`xox[abposr]-[A-Za-z0-9\-]{10,}`),
+	regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/\-]{8,}=*`),
+	regexp.MustCompile(`(?i)([?&](?:key|api_key|apikey|access_token|token)=)[^&\s"']+`),
+	regexp.MustCompile(`(?i)((?:x-api-key|x-goog-api-key|api-key|authorization)"?\s*[:=]\s*"?)[^\s",}]+`),
+}
+
+// Register adds values, such as API keys read from config, to be masked
+// wherever they appear.
+func Register(values ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, v := range values {
+		if v = strings.TrimSpace(v); len(v) >= minSecretLen {
+			secrets[v] = true
+		}
+	}
+}
+
+func known() []string {
+	mu.RLock()
+	list := make([]string, 0, len(secrets))
+	for s := range secrets {
+		list = append(list, s)
+	}
+	mu.RUnlock()
+
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		for _, suffix := range envSuffixes {
+			if strings.HasSuffix(strings.ToUpper(name), suffix) && len(value) >= minSecretLen {
+				list = append(list, value)
+			}
+		}
+	}
+
+	// Longest first, so a key that contains another is masked whole.
+	sort.Slice(list, func(i, j int) bool { return len(list[i]) > len(list[j]) })
+	return list
+}
+
+// String masks every registered secret and known token pattern in s.
+func String(s string) string {
+	if s == "" {
+		return s
+	}
+	for _, secret := range known() {
+		s = strings.ReplaceAll(s, secret, mask)
+	}
+	for _, re := range patterns {
+		if re.NumSubexp() > 0 {
+			s = re.ReplaceAllString(s, "${1}"+mask)
+		} else {
+			s = re.ReplaceAllString(s, mask)
+		}
+	}
+	return s
+}
+
+// Error wraps err so its message is redacted. errors.Is and errors.As still
+// see the original error.
+func Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*redacted); ok {
+		return err
+	}
+	return &redacted{err}
+}
+
+type redacted struct{ err error }
+
+func (r *redacted) Error() string { return String(r.err.Error()) }
+func (r *redacted) Unwrap() error { return r.err }
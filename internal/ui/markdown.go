@@ -2,6 +2,7 @@ package ui
 
 import (
 	"fmt"
+	"io"
 	"regexp"
 	"strings"
 
@@ -39,45 +40,88 @@ func NewMarkdownRenderer() *MarkdownRenderer {
 }
 
 func (m *MarkdownRenderer) Render(text string) string {
-	lines := strings.Split(text, "\n")
-	var result []string
-	inCodeBlock := false
-	codeLanguage := ""
-
-	for i, line := range lines {
-		if strings.HasPrefix(strings.TrimSpace(line), "```") {
-			inCodeBlock = !inCodeBlock
-			if inCodeBlock {
-				parts := strings.Fields(strings.TrimSpace(line))
-				if len(parts) > 1 {
-					codeLanguage = parts[1]
-				}
-				result = append(result, "")
-				result = append(result, m.code("  ┌─ Code: "+codeLanguage))
-			} else {
-				result = append(result, m.code("  └─"))
-				result = append(result, "")
-				codeLanguage = ""
-			}
-			continue
-		}
+	var sb strings.Builder
+	stream := m.NewStream(&sb)
+	stream.Feed(text)
+	stream.Flush()
+	return strings.TrimSuffix(sb.String(), "\n")
+}
 
-		if inCodeBlock {
-			result = append(result, m.renderCodeLine(line))
-			continue
+type MarkdownStream struct {
+	renderer     *MarkdownRenderer
+	out          io.Writer
+	pending      string
+	inCodeBlock  bool
+	codeLanguage string
+	afterHeading bool
+}
+
+func (m *MarkdownRenderer) NewStream(out io.Writer) *MarkdownStream {
+	return &MarkdownStream{renderer: m, out: out}
+}
+
+func (s *MarkdownStream) Feed(text string) {
+	s.pending += text
+	for {
+		idx := strings.IndexByte(s.pending, '\n')
+		if idx < 0 {
+			return
 		}
+		line := strings.TrimSuffix(s.pending[:idx], "\r")
+		s.pending = s.pending[idx+1:]
+		s.renderLine(line)
+	}
+}
 
-		rendered := m.renderLine(line)
-		result = append(result, rendered)
+func (s *MarkdownStream) Flush() {
+	if s.pending != "" {
+		line := s.pending
+		s.pending = ""
+		s.renderLine(line)
+	}
+}
+
+func (s *MarkdownStream) emit(line string) {
+	fmt.Fprintln(s.out, line)
+}
+
+func (s *MarkdownStream) renderLine(line string) {
+	m := s.renderer
+	trimmed := strings.TrimSpace(line)
 
-		if strings.HasPrefix(strings.TrimSpace(line), "#") && i < len(lines)-1 {
-			if !strings.HasPrefix(strings.TrimSpace(lines[i+1]), "#") {
-				result = append(result, "")
+	if strings.HasPrefix(trimmed, "```") {
+		if s.afterHeading {
+			s.emit("")
+			s.afterHeading = false
+		}
+		s.inCodeBlock = !s.inCodeBlock
+		if s.inCodeBlock {
+			parts := strings.Fields(trimmed)
+			if len(parts) > 1 {
+				s.codeLanguage = parts[1]
 			}
+			s.emit("")
+			s.emit(m.code("  ┌─ Code: " + s.codeLanguage))
+		} else {
+			s.emit(m.code("  └─"))
+			s.emit("")
+			s.codeLanguage = ""
 		}
+		return
+	}
+
+	if s.inCodeBlock {
+		s.emit(m.renderCodeLine(line))
+		return
+	}
+
+	isHeading := strings.HasPrefix(trimmed, "#")
+	if s.afterHeading && !isHeading {
+		s.emit("")
 	}
+	s.afterHeading = isHeading
 
-	return strings.Join(result, "\n")
+	s.emit(m.renderLine(line))
 }
 
 func (m *MarkdownRenderer) renderLine(line string) string {
@@ -182,6 +226,6 @@ func (m *MarkdownRenderer) StreamRender(text string, charDelay int) {
 			continue
 		}
 
-		fmt.Println(line) 
+		fmt.Println(line)
 	}
 }
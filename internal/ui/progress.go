@@ -0,0 +1,77 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+type ProgressBar struct {
+	label  string
+	width  int
+	active bool
+}
+
+func NewProgressBar(label string) *ProgressBar {
+	return &ProgressBar{label: label, width: 30}
+}
+
+func (p *ProgressBar) SetLabel(label string) {
+	if p.active && label != p.label {
+		p.Done()
+	}
+	p.label = label
+}
+
+func (p *ProgressBar) Update(completed, total int64) {
+	cBar := color.New(color.FgCyan).SprintFunc()
+	cSubtle := color.New(color.FgHiBlack).SprintFunc()
+
+	if total <= 0 {
+		fmt.Printf("\r\033[K  %s", p.label)
+		p.active = true
+		return
+	}
+	if completed > total {
+		completed = total
+	}
+
+	filled := int(float64(p.width) * float64(completed) / float64(total))
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", p.width-filled)
+	percent := float64(completed) * 100 / float64(total)
+
+	fmt.Printf("\r\033[K  %-24s %s %5.1f%% %s",
+		truncateLabel(p.label, 24),
+		cBar(bar),
+		percent,
+		cSubtle(FormatBytes(completed)+" / "+FormatBytes(total)))
+	p.active = true
+}
+
+func (p *ProgressBar) Done() {
+	if p.active {
+		fmt.Println()
+		p.active = false
+	}
+}
+
+func truncateLabel(label string, max int) string {
+	if len(label) <= max {
+		return label
+	}
+	return label[:max-3] + "..."
+}
+
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -7,9 +7,11 @@ import (
 )
 
 type Spinner struct {
-	stop    chan bool
-	wg      sync.WaitGroup
-	message string
+	stop     chan bool
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	message  string
+	stopOnce sync.Once
 }
 
 func NewSpinner(msg string) *Spinner {
@@ -21,6 +23,7 @@ func NewSpinner(msg string) *Spinner {
 
 func (s *Spinner) Start() {
 	s.wg.Add(1)
+	fmt.Print("\033[?25l")
 	go func() {
 		defer s.wg.Done()
 		frames := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
@@ -28,10 +31,13 @@ func (s *Spinner) Start() {
 		for {
 			select {
 			case <-s.stop:
-				fmt.Printf("\r\033[K")
+				fmt.Printf("\r\033[K\033[?25h")
 				return
 			default:
-				fmt.Printf("\r\033[36m%s\033[0m %s ", frames[i%len(frames)], s.message)
+				s.mu.Lock()
+				msg := s.message
+				s.mu.Unlock()
+				fmt.Printf("\r\033[36m%s\033[0m %s \033[K", frames[i%len(frames)], msg)
 				i++
 				time.Sleep(100 * time.Millisecond)
 			}
@@ -39,7 +45,16 @@ func (s *Spinner) Start() {
 	}()
 }
 
+// SetMessage replaces the text shown next to the spinner while it runs.
+func (s *Spinner) SetMessage(msg string) {
+	s.mu.Lock()
+	s.message = msg
+	s.mu.Unlock()
+}
+
 func (s *Spinner) Stop() {
-	s.stop <- true
-	s.wg.Wait()
-}
\ No newline at end of file
+	s.stopOnce.Do(func() {
+		s.stop <- true
+		s.wg.Wait()
+	})
+}
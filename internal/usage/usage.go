@@ -0,0 +1,233 @@
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/broman0x/forgeai-cli/internal/config"
+)
+
+const (
+	retentionDays = 90
+
+	lockTimeout = 5 * time.Second
+	staleLock   = 30 * time.Second
+)
+
+type Entry struct {
+	Day          string  `json:"day"`
+	Session      string  `json:"session"`
+	Provider     string  `json:"provider"`
+	Model        string  `json:"model"`
+	Command      string  `json:"command"`
+	Requests     int     `json:"requests"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	Cost         float64 `json:"cost"`
+}
+
+type Ledger struct {
+	Entries []Entry `json:"entries"`
+}
+
+type commandKey struct{}
+
+var (
+	mu        sync.Mutex
+	sessionID = time.Now().Format("20060102-150405")
+)
+
+func WithCommand(ctx context.Context, command string) context.Context {
+	return context.WithValue(ctx, commandKey{}, command)
+}
+
+func CommandFrom(ctx context.Context) string {
+	if cmd, ok := ctx.Value(commandKey{}).(string); ok && cmd != "" {
+		return cmd
+	}
+	return "other"
+}
+
+func SessionID() string {
+	return sessionID
+}
+
+func GetLedgerPath() string {
+	return filepath.Join(config.GetConfigDir(), "usage.json")
+}
+
+func Load() (*Ledger, error) {
+	path := GetLedgerPath()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Ledger{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ledger Ledger
+	if err := json.Unmarshal(data, &ledger); err != nil {
+		return nil, fmt.Errorf("usage ledger %s is corrupt: %v", path, err)
+	}
+	return &ledger, nil
+}
+
+// save writes the ledger atomically, so a crash mid-write leaves the old one.
+func save(ledger *Ledger) error {
+	data, err := json.MarshalIndent(ledger, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := GetLedgerPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, GetLedgerPath())
+}
+
+// lock keeps other forge processes from adding to the ledger at the same
+// time, which would drop one side's entries. A lock older than staleLock is
+// left over from a crash and taken over.
+func lock() (func(), error) {
+	path := GetLedgerPath() + ".lock"
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLock {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("usage ledger is locked by another process (%s)", path)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func Add(ctx context.Context, provider, model string, inputTokens, outputTokens int, cost float64) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	unlock, err := lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	ledger, err := Load()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	day := now.Format("2006-01-02")
+	command := CommandFrom(ctx)
+
+	found := false
+	for i := range ledger.Entries {
+		e := &ledger.Entries[i]
+		if e.Day == day && e.Session == sessionID && e.Provider == provider && e.Model == model && e.Command == command {
+			e.Requests++
+			e.InputTokens += inputTokens
+			e.OutputTokens += outputTokens
+			e.Cost += cost
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		ledger.Entries = append(ledger.Entries, Entry{
+			Day:          day,
+			Session:      sessionID,
+			Provider:     provider,
+			Model:        model,
+			Command:      command,
+			Requests:     1,
+			InputTokens:  inputTokens,
+			OutputTokens: outputTokens,
+			Cost:         cost,
+		})
+	}
+
+	cutoff := now.AddDate(0, 0, -retentionDays).Format("2006-01-02")
+	kept := ledger.Entries[:0]
+	for _, e := range ledger.Entries {
+		if e.Day >= cutoff {
+			kept = append(kept, e)
+		}
+	}
+	ledger.Entries = kept
+
+	return save(ledger)
+}
+
+func (l *Ledger) Since(day string) []Entry {
+	var out []Entry
+	for _, e := range l.Entries {
+		if e.Day >= day {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func (l *Ledger) LastSession() (string, []Entry) {
+	last := ""
+	for _, e := range l.Entries {
+		if e.Session > last {
+			last = e.Session
+		}
+	}
+
+	var out []Entry
+	for _, e := range l.Entries {
+		if e.Session == last {
+			out = append(out, e)
+		}
+	}
+	return last, out
+}
+
+func Summarize(entries []Entry, keyFn func(Entry) string) []Entry {
+	totals := map[string]*Entry{}
+	var keys []string
+
+	for _, e := range entries {
+		key := keyFn(e)
+		t, ok := totals[key]
+		if !ok {
+			t = &Entry{Day: e.Day, Session: e.Session, Provider: e.Provider, Model: e.Model, Command: e.Command}
+			totals[key] = t
+			keys = append(keys, key)
+		}
+		t.Requests += e.Requests
+		t.InputTokens += e.InputTokens
+		t.OutputTokens += e.OutputTokens
+		t.Cost += e.Cost
+	}
+
+	sort.Strings(keys)
+	out := make([]Entry, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, *totals[k])
+	}
+	return out
+}
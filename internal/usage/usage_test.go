@@ -0,0 +1,132 @@
+package usage_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/broman0x/forgeai-cli/internal/usage"
+)
+
+// With FORGEAI_USAGE_CHILD set the test binary only adds to the ledger, so
+// several processes can write to it at once.
+func TestMain(m *testing.M) {
+	if os.Getenv("FORGEAI_USAGE_CHILD") != "" {
+		for i := 0; i < 10; i++ {
+			if err := usage.Add(context.Background(), "openai", "gpt-4o", 1, 1, 0.5); err != nil {
+				os.Exit(1)
+			}
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func isolate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("APPDATA", "")
+}
+
+func TestAddMergesBySessionAndCommand(t *testing.T) {
+	isolate(t)
+	ctx := usage.WithCommand(context.Background(), "review")
+	usage.Add(ctx, "openai", "gpt-4o", 100, 20, 0.01)
+	usage.Add(ctx, "openai", "gpt-4o", 50, 10, 0.02)
+	usage.Add(context.Background(), "openai", "gpt-4o", 1, 1, 0)
+
+	ledger, err := usage.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ledger.Entries) != 2 {
+		t.Fatalf("entries %+v", ledger.Entries)
+	}
+	review := ledger.Entries[0]
+	if review.Command != "review" || review.Requests != 2 || review.InputTokens != 150 || review.OutputTokens != 30 || review.Cost < 0.0299 || review.Cost > 0.0301 {
+		t.Fatalf("review entry %+v", review)
+	}
+	if ledger.Entries[1].Command != "other" {
+		t.Fatalf("commands without a name go under other: %+v", ledger.Entries[1])
+	}
+}
+
+func TestAddDropsEntriesPastRetention(t *testing.T) {
+	isolate(t)
+	old := time.Now().AddDate(0, 0, -91).Format("2006-01-02")
+	os.MkdirAll(strings.TrimSuffix(usage.GetLedgerPath(), "usage.json"), 0755)
+	os.WriteFile(usage.GetLedgerPath(), []byte(`{"entries":[{"day":"`+old+`","session":"s","provider":"gemini","requests":1}]}`), 0644)
+
+	usage.Add(context.Background(), "openai", "gpt-4o", 1, 1, 0)
+	ledger, _ := usage.Load()
+	if len(ledger.Entries) != 1 || ledger.Entries[0].Provider != "openai" {
+		t.Fatalf("entries %+v", ledger.Entries)
+	}
+}
+
+func TestCorruptLedgerIsNotOverwritten(t *testing.T) {
+	isolate(t)
+	path := usage.GetLedgerPath()
+	os.MkdirAll(strings.TrimSuffix(path, "usage.json"), 0755)
+	truncated := []byte(`{"entries":[{"day":"2026-10-01","provider":"open`)
+	os.WriteFile(path, truncated, 0644)
+
+	if _, err := usage.Load(); err == nil || !strings.Contains(err.Error(), "corrupt") {
+		t.Fatalf("Load should report the corrupt ledger, got %v", err)
+	}
+	if err := usage.Add(context.Background(), "openai", "gpt-4o", 1, 1, 0); err == nil {
+		t.Fatal("Add should fail rather than replace the ledger")
+	}
+	if data, _ := os.ReadFile(path); string(data) != string(truncated) {
+		t.Fatalf("ledger was rewritten: %s", data)
+	}
+}
+
+func TestConcurrentProcessesKeepEveryEntry(t *testing.T) {
+	isolate(t)
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cmd := exec.Command(os.Args[0])
+			cmd.Env = append(os.Environ(), "FORGEAI_USAGE_CHILD=1")
+			errs <- cmd.Run()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ledger, err := usage.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := 0
+	for _, e := range ledger.Entries {
+		requests += e.Requests
+	}
+	if requests != 40 {
+		t.Fatalf("got %d requests recorded, want 40", requests)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	entries := []usage.Entry{
+		{Provider: "openai", Model: "gpt-4o", Requests: 1, InputTokens: 10, Cost: 0.1},
+		{Provider: "gemini", Model: "gemini-2.5-flash", Requests: 2, InputTokens: 5},
+		{Provider: "openai", Model: "gpt-4o-mini", Requests: 3, InputTokens: 1, Cost: 0.2},
+	}
+	got := usage.Summarize(entries, func(e usage.Entry) string { return e.Provider })
+	if len(got) != 2 || got[0].Provider != "gemini" || got[1].Requests != 4 || got[1].InputTokens != 11 || got[1].Cost < 0.2999 {
+		t.Fatalf("summary %+v", got)
+	}
+}
@@ -0,0 +1,338 @@
+// Package workspace provides read-only tools that let a model look around the
+// project it is chatting about. Every path is resolved inside the workspace
+// root; anything outside it, including via symlinks, is refused.
+package workspace
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/broman0x/forgeai-cli/internal/ai"
+)
+
+const (
+	maxListEntries = 500
+	maxReadBytes   = 100 * 1024
+	maxGrepMatches = 100
+	maxGrepFile    = 1024 * 1024
+	maxGrepLine    = 200
+	maxLogEntries  = 50
+)
+
+var skipDirs = map[string]bool{
+	".git": true, "node_modules": true, "vendor": true, "target": true, "build": true,
+}
+
+type Workspace struct {
+	Root string
+}
+
+// New resolves root once so later containment checks compare real paths.
+func New(root string) (*Workspace, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	real, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return nil, err
+	}
+	return &Workspace{Root: real}, nil
+}
+
+func (w *Workspace) resolve(path string) (string, error) {
+	if path == "" {
+		path = "."
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(w.Root, path)
+	}
+	real, err := filepath.EvalSymlinks(filepath.Clean(path))
+	if err != nil {
+		return "", fmt.Errorf("%s: no such file or directory", w.rel(path))
+	}
+	rel, err := filepath.Rel(w.Root, real)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the workspace", path)
+	}
+	return real, nil
+}
+
+func (w *Workspace) rel(path string) string {
+	if rel, err := filepath.Rel(w.Root, path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// Tools returns list_directory, read_file, grep and git_log bound to w.
+func (w *Workspace) Tools() []ai.Tool {
+	return []ai.Tool{
+		{
+			Name:        "list_directory",
+			Description: "List the files and subdirectories of a directory in the workspace. Directories end with a slash.",
+			Parameters: object(map[string]interface{}{
+				"path": str("Directory relative to the workspace root. Defaults to the root."),
+			}),
+			Run: w.listDirectory,
+		},
+		{
+			Name:        "read_file",
+			Description: "Read a text file from the workspace, with line numbers. Use start_line and end_line for large files.",
+			Parameters: object(map[string]interface{}{
+				"path":       str("File path relative to the workspace root."),
+				"start_line": integer("First line to return, 1-based."),
+				"end_line":   integer("Last line to return, inclusive."),
+			}, "path"),
+			Run: w.readFile,
+		},
+		{
+			Name:        "grep",
+			Description: "Search workspace files for a regular expression (RE2 syntax). Returns path:line: text for each match.",
+			Parameters: object(map[string]interface{}{
+				"pattern": str("Regular expression to search for."),
+				"path":    str("File or directory to search, relative to the workspace root. Defaults to the root."),
+				"include": str("Only search files whose name matches this glob, e.g. *.go."),
+			}, "pattern"),
+			Run: w.grep,
+		},
+		{
+			Name:        "git_log",
+			Description: "Show recent commits of the workspace git repository, optionally limited to one path.",
+			Parameters: object(map[string]interface{}{
+				"path":  str("Only show commits touching this path."),
+				"limit": integer("Number of commits to show, at most 50. Defaults to 10."),
+			}),
+			Run: w.gitLog,
+		},
+	}
+}
+
+func object(props map[string]interface{}, required ...string) map[string]interface{} {
+	schema := map[string]interface{}{"type": "object", "properties": props}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func str(desc string) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "description": desc}
+}
+
+func integer(desc string) map[string]interface{} {
+	return map[string]interface{}{"type": "integer", "description": desc}
+}
+
+func (w *Workspace) listDirectory(_ context.Context, raw json.RawMessage) (string, error) {
+	var args struct {
+		Path string `json:"path"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %v", err)
+	}
+
+	dir, err := w.resolve(args.Path)
+	if err != nil {
+		return "", err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("%s is not a readable directory", w.rel(dir))
+	}
+
+	var sb strings.Builder
+	for i, e := range entries {
+		if i == maxListEntries {
+			fmt.Fprintf(&sb, "... %d more entries\n", len(entries)-i)
+			break
+		}
+		if e.IsDir() {
+			fmt.Fprintf(&sb, "%s/\n", e.Name())
+			continue
+		}
+		if info, err := e.Info(); err == nil {
+			fmt.Fprintf(&sb, "%s (%d bytes)\n", e.Name(), info.Size())
+		} else {
+			fmt.Fprintf(&sb, "%s\n", e.Name())
+		}
+	}
+	if sb.Len() == 0 {
+		return "(empty directory)", nil
+	}
+	return sb.String(), nil
+}
+
+func (w *Workspace) readFile(_ context.Context, raw json.RawMessage) (string, error) {
+	var args struct {
+		Path      string `json:"path"`
+		StartLine int    `json:"start_line"`
+		EndLine   int    `json:"end_line"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %v", err)
+	}
+
+	path, err := w.resolve(args.Path)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("%s is not a readable file", w.rel(path))
+	}
+	if isBinary(data) {
+		return "", fmt.Errorf("%s is a binary file", w.rel(path))
+	}
+
+	lines := strings.Split(string(data), "\n")
+	start, end := 1, len(lines)
+	if args.StartLine > 0 {
+		start = args.StartLine
+	}
+	if args.EndLine > 0 && args.EndLine < end {
+		end = args.EndLine
+	}
+	if start > end {
+		return "", fmt.Errorf("%s has %d lines", w.rel(path), len(lines))
+	}
+
+	var sb strings.Builder
+	for i := start; i <= end; i++ {
+		if sb.Len() > maxReadBytes {
+			fmt.Fprintf(&sb, "... truncated at line %d of %d, use start_line to read on\n", i-1, len(lines))
+			break
+		}
+		fmt.Fprintf(&sb, "%5d  %s\n", i, lines[i-1])
+	}
+	return sb.String(), nil
+}
+
+func (w *Workspace) grep(ctx context.Context, raw json.RawMessage) (string, error) {
+	var args struct {
+		Pattern string `json:"pattern"`
+		Path    string `json:"path"`
+		Include string `json:"include"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %v", err)
+	}
+	re, err := regexp.Compile(args.Pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern: %v", err)
+	}
+	start, err := w.resolve(args.Path)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	matches := 0
+	err = filepath.WalkDir(start, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() {
+			if path != start && skipDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if args.Include != "" {
+			if ok, _ := filepath.Match(args.Include, d.Name()); !ok {
+				return nil
+			}
+		}
+		if info, err := d.Info(); err != nil || !info.Mode().IsRegular() || info.Size() > maxGrepFile {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil || isBinary(data) {
+			return nil
+		}
+
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 0, 64*1024), maxGrepFile)
+		for n := 1; scanner.Scan(); n++ {
+			line := scanner.Text()
+			if !re.MatchString(line) {
+				continue
+			}
+			if len(line) > maxGrepLine {
+				line = line[:maxGrepLine] + "..."
+			}
+			fmt.Fprintf(&sb, "%s:%d: %s\n", w.rel(path), n, strings.TrimSpace(line))
+			matches++
+			if matches == maxGrepMatches {
+				return fs.SkipAll
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if matches == 0 {
+		return "no matches", nil
+	}
+	if matches == maxGrepMatches {
+		fmt.Fprintf(&sb, "... stopped after %d matches, narrow the pattern or path\n", maxGrepMatches)
+	}
+	return sb.String(), nil
+}
+
+func (w *Workspace) gitLog(ctx context.Context, raw json.RawMessage) (string, error) {
+	var args struct {
+		Path  string `json:"path"`
+		Limit int    `json:"limit"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %v", err)
+	}
+	if args.Limit <= 0 {
+		args.Limit = 10
+	}
+	if args.Limit > maxLogEntries {
+		args.Limit = maxLogEntries
+	}
+
+	gitArgs := []string{"log", "--date=short", "--pretty=format:%h %ad %an: %s", "-n", fmt.Sprint(args.Limit)}
+	if args.Path != "" {
+		path, err := w.resolve(args.Path)
+		if err != nil {
+			return "", err
+		}
+		gitArgs = append(gitArgs, "--", path)
+	}
+
+	cmd := exec.CommandContext(ctx, "git", gitArgs...)
+	cmd.Dir = w.Root
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git log failed: %s", strings.TrimSpace(string(out)))
+	}
+	if len(out) == 0 {
+		return "no commits", nil
+	}
+	return string(out), nil
+}
+
+func isBinary(data []byte) bool {
+	if len(data) > 8000 {
+		data = data[:8000]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
@@ -0,0 +1,90 @@
+package workspace
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestWorkspace(t *testing.T) *Workspace {
+	t.Helper()
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "pkg"), 0755)
+	os.MkdirAll(filepath.Join(root, "node_modules", "dep"), 0755)
+	os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+	os.WriteFile(filepath.Join(root, "pkg", "util.go"), []byte("package pkg\n\nfunc Helper() {}\n"), 0644)
+	os.WriteFile(filepath.Join(root, "node_modules", "dep", "index.js"), []byte("function Helper() {}\n"), 0644)
+
+	w, err := New(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func run(t *testing.T, w *Workspace, name, args string) (string, error) {
+	t.Helper()
+	for _, tool := range w.Tools() {
+		if tool.Name == name {
+			return tool.Run(context.Background(), json.RawMessage(args))
+		}
+	}
+	t.Fatalf("no tool %q", name)
+	return "", nil
+}
+
+func TestReadFileWithLineRange(t *testing.T) {
+	w := newTestWorkspace(t)
+	out, err := run(t, w, "read_file", `{"path":"main.go","start_line":3,"end_line":3}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(out) != "3  func main() {}" {
+		t.Fatalf("out = %q", out)
+	}
+}
+
+func TestPathsOutsideWorkspaceAreRefused(t *testing.T) {
+	w := newTestWorkspace(t)
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644)
+	if err := os.Symlink(outside, filepath.Join(w.Root, "link")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+
+	for _, path := range []string{
+		filepath.ToSlash(filepath.Join(outside, "secret.txt")),
+		"link/secret.txt",
+		"../" + filepath.Base(outside) + "/secret.txt",
+	} {
+		out, err := run(t, w, "read_file", `{"path":"`+path+`"}`)
+		if err == nil || strings.Contains(out, "secret") {
+			t.Fatalf("read_file %s = %q, %v; want refusal", path, out, err)
+		}
+	}
+}
+
+func TestGrepSkipsVendoredDirectories(t *testing.T) {
+	w := newTestWorkspace(t)
+	out, err := run(t, w, "grep", `{"pattern":"func Helper"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "pkg/util.go:3:") || strings.Contains(out, "node_modules") {
+		t.Fatalf("out = %q", out)
+	}
+}
+
+func TestListDirectoryMarksDirectories(t *testing.T) {
+	w := newTestWorkspace(t)
+	out, err := run(t, w, "list_directory", `{}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "pkg/\n") || !strings.Contains(out, "main.go (") {
+		t.Fatalf("out = %q", out)
+	}
+}